@@ -0,0 +1,40 @@
+# Listener of the streaming queries that applies the replicas written by the adaptive system in Redis
+# (backend spark): the executors with requestTotalExecutors and the shuffle partitions with the SQL conf.
+#
+#   from sps_listener import SpsListener
+#   spark.conf.set("spark.sql.streaming.metricsEnabled", "true")
+#   spark.streams.addListener(SpsListener(spark, redis.Redis(host="localhost", port=6379)))
+#
+# The shuffle partitions of a stateful query are fixed by its checkpoint, so they only apply to stateless
+# queries and to queries started afterwards.
+from pyspark.sql.streaming import StreamingQueryListener
+
+
+class SpsListener(StreamingQueryListener):
+    def __init__(self, spark, redis, prefix="spark."):
+        self.spark = spark
+        self.redis = redis
+        self.prefix = prefix
+        self.executors = None
+        self.partitions = None
+
+    def onQueryStarted(self, event):
+        pass
+
+    def onQueryProgress(self, event):
+        executors = self.redis.get(self.prefix + "executors")
+        if executors is not None and int(executors) != self.executors:
+            self.executors = int(executors)
+            sc = self.spark.sparkContext
+            # requestTotalExecutors is a developer API of SparkContext, reached through the JVM
+            sc._jsc.sc().requestTotalExecutors(self.executors, 0, sc._jvm.PythonUtils.toScalaMap({}))
+        partitions = self.redis.get(self.prefix + "shuffle_partitions")
+        if partitions is not None and int(partitions) != self.partitions:
+            self.partitions = int(partitions)
+            self.spark.conf.set("spark.sql.shuffle.partitions", str(self.partitions))
+
+    def onQueryIdle(self, event):
+        pass
+
+    def onQueryTerminated(self, event):
+        pass
@@ -4,50 +4,77 @@ import (
 	"github.com/dwladdimiroc/sps-storm/internal/predictive"
 	"github.com/dwladdimiroc/sps-storm/internal/storm"
 	"github.com/dwladdimiroc/sps-storm/internal/util"
-	"github.com/spf13/viper"
 	"log"
 	"math"
 )
 
-func analyze(topology *storm.Topology) {
+func (c *Controller) analyze(topology *storm.Topology) {
 	//log.Printf("analyze: period %v\n", period)
-	if period%viper.GetInt("storm.adaptive.analyze_samples") == 0 {
-		log.Printf("[t=%d] analyze: prediction\n", period)
-		// Safe prediction - This function adds the p next input rate according the simple prediction
-		simplesPrediction := predictive.Simple(topology)
-		for i := 0; i < len(simplesPrediction); i++ {
-			topology.PredictedInputRate = append(topology.PredictedInputRate, int64(simplesPrediction[i]))
-		}
+	if c.predictionPeriod(c.period) {
+		c.predictInput(topology, c.predictor.Forecast(c.predictor.Samples(topology)), c.period)
+	}
 
-		predictive.PredictInput(topology)
+	//log.Printf("input predicted: %d\n", input)
+	if c.planningPeriod(c.period) || c.deferredPlan {
+		c.determinateReplicas(topology, c.period)
+	}
+}
 
-		for i := range topology.Bolts {
-			topology.Bolts[i].PredictionQueue = predictionInputQueue(topology.Bolts[i], *topology) / viper.GetInt64("storm.adaptive.analyze_samples")
-		}
+func (c *Controller) predictionPeriod(period int) bool {
+	return period%c.settings.AnalyzeSamples == 0
+}
+
+func (c *Controller) planningPeriod(period int) bool {
+	return period >= c.settings.AnalyzeSamples && period%c.settings.PlanningSamples == 0
+}
 
-		topology.ClearQueue()
+// predictInput adds the forecast of the predictor, made in the window of the given period, to the predicted input
+// rate of the topology.
+func (c *Controller) predictInput(topology *storm.Topology, forecast []float64, window int) {
+	log.Printf("[t=%d] analyze: prediction\n", window)
+	// Safe prediction - This function adds the p next input rate according the simple prediction
+	simplesPrediction := predictive.Simple(topology)
+	for i := 0; i < len(simplesPrediction); i++ {
+		topology.PredictedInputRate = append(topology.PredictedInputRate, int64(simplesPrediction[i]))
+	}
+
+	c.predictor.Append(forecast)
 
-		//log.Printf("[t=%d] analyze: predictedModel={%s},predictedInput={%d},topologyInput={%d}", period, predictive.GetPred().NameModel, len(predictive.GetPred().PredictedInput), len(topology.PredictedInputRate))
-		init := len(predictive.GetPred().PredictedInput) - viper.GetInt("storm.adaptive.prediction_number")
-		for i := init; i < len(predictive.GetPred().PredictedInput); i++ {
-			topology.PredictedInputRate[i] = int64(predictive.GetPred().PredictedInput[i])
+	for i := range topology.Bolts {
+		topology.Bolts[i].PredictionQueue = predictionInputQueue(topology.Bolts[i], *topology) / int64(c.settings.AnalyzeSamples)
+		// The consumer lag waits before the spouts, so the bolts fed by them receive it as well
+		if c.settings.PlanLag && readsSpout(topology.Bolts[i], *topology) {
+			topology.Bolts[i].PredictionQueue += topology.Lag
 		}
 	}
 
-	//log.Printf("input predicted: %d\n", input)
-	if period >= viper.GetInt("storm.adaptive.analyze_samples") && period%viper.GetInt("storm.adaptive.planning_samples") == 0 {
-		log.Printf("[t=%d] analyze: determinate replicas\n", period)
-		for i := range topology.Bolts {
-			var predictedInput int64
-			for j := 0; j < viper.GetInt("storm.adaptive.planning_samples"); j++ {
-				predictedInput += predictive.GetPredictedInputPeriod(period + j)
-			}
-			predictedInput /= viper.GetInt64("storm.adaptive.planning_samples")
-			predictedInput += topology.Bolts[i].PredictionQueue
-			topology.Bolts[i].PredictionReplicas = predictionReplicas(predictedInput, topology.Bolts[i])
-			//log.Printf("[t=%d] analyze: bolt={%s},predictionInput={%d},predictionReplicas={%d}", period, topology.Bolts[i].Name, predictedInput, topology.Bolts[i].PredictionReplicas)
+	topology.ClearQueue()
+
+	//log.Printf("[t=%d] analyze: predictedModel={%s},predictedInput={%d},topologyInput={%d}", period, predictor.Get().NameModel, len(predictor.Get().PredictedInput), len(topology.PredictedInputRate))
+	init := len(c.predictor.Get().PredictedInput) - c.settings.PredictionNumber
+	for i := init; i < len(c.predictor.Get().PredictedInput); i++ {
+		topology.PredictedInputRate[i] = int64(c.predictor.Get().PredictedInput[i])
+	}
+}
+
+// determinateReplicas predicts the replicas of each bolt for the planning window that starts at the given period
+// and, with the internal planner, plans them.
+func (c *Controller) determinateReplicas(topology *storm.Topology, window int) {
+	log.Printf("[t=%d] analyze: determinate replicas\n", window)
+	profile := c.settings.Profile(util.Now())
+	topology.Profile = profile.Name
+	for i := range topology.Bolts {
+		var predictedInput int64
+		for j := 0; j < c.settings.PlanningSamples; j++ {
+			predictedInput += c.predictor.PredictedInputPeriod(window + j)
 		}
-		planning(topology)
+		predictedInput /= int64(c.settings.PlanningSamples)
+		predictedInput += topology.Bolts[i].PredictionQueue
+		topology.Bolts[i].PredictionReplicas = c.predictionReplicas(predictedInput, topology.Bolts[i], profile)
+		//log.Printf("[t=%d] analyze: bolt={%s},predictionInput={%d},predictionReplicas={%d}", period, topology.Bolts[i].Name, predictedInput, topology.Bolts[i].PredictionReplicas)
+	}
+	if c.settings.Planner != "external" {
+		c.planning(topology, profile, window)
 	}
 }
 
@@ -64,10 +91,22 @@ func predictionInputQueue(bolt storm.Bolt, topology storm.Topology) int64 {
 	return valuePredictionQ
 }
 
-func predictionReplicas(input int64, bolt storm.Bolt) int64 {
+func readsSpout(bolt storm.Bolt, topology storm.Topology) bool {
+	for _, predecessor := range bolt.BoltsPredecessor {
+		for _, spout := range topology.Spouts {
+			if spout.Name == predecessor {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Controller) predictionReplicas(input int64, bolt storm.Bolt, profile util.Profile) int64 {
 	executedTimeAvg := chooseExecutedTime(bolt)
-	timeWindow := float64(int64(viper.GetInt("storm.adaptive.time_window_size")) * util.SECS)
-	replicasPredictive := float64(input) * executedTimeAvg / timeWindow
+	timeWindow := float64(c.settings.TimeWindowSize * util.SECS)
+	utilization := c.settings.Bolt(bolt.Name).SlaUtilization
+	replicasPredictive := float64(input) * executedTimeAvg / (timeWindow * utilization) * (1 + profile.Headroom)
 	//log.Printf("analyze: prediction replicas={%v},input={%v},execTime={%v},timeWindow={%v}\n", replicasPredictive, input, executedTimeAvg, timeWindow)
 	return int64(math.Ceil(replicasPredictive))
 }
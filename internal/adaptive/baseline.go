@@ -0,0 +1,99 @@
+package adaptive
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/spf13/viper"
+)
+
+// baselineState holds the baseline replicas learned by a Controller
+type baselineState struct {
+	// baselines are the learned baselines of storm.adaptive.baseline_file, by topology name and mode
+	// (<name>/<mode>), so the baseline of a topology is not used by another one, nor learned in one mode and used
+	// in the other
+	baselines map[string]map[string]int64
+	// learnedBaseline are the baseline replicas of each bolt learned with storm.adaptive.baseline initial or max,
+	// the baseline of the topology and the mode in baselines
+	learnedBaseline map[string]int64
+	// baselineChanged is set when learnedBaseline changed since it was saved
+	baselineChanged bool
+}
+
+// initBaseline loads the learned baseline of the topology and the mode from storm.adaptive.baseline_file, and
+// learns the bolts that are not in it from the executors deployed in the cluster (the current replicas when the
+// cluster does not report them). The file keeps the initial replicas of a topology across restarts, when the
+// deployed ones were already adapted. The topology is identified by its name, since its id changes on each deploy.
+func (c *Controller) initBaseline(summaryTopology storm.SummaryTopology) {
+	c.baselines = make(map[string]map[string]int64)
+	c.learnedBaseline = make(map[string]int64)
+	c.baselineChanged = false
+	if c.settings.Baseline == "config" {
+		return
+	}
+
+	file := viper.GetString("storm.adaptive.baseline_file")
+	if data, err := os.ReadFile(file); err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("baseline: error={%v}\n", err)
+		}
+	} else if err := json.Unmarshal(data, &c.baselines); err != nil {
+		log.Printf("baseline: file={%s},error={%v}\n", file, err)
+		c.baselines = make(map[string]map[string]int64)
+	}
+	name := summaryTopology.Name
+	if name == "" {
+		name = c.topology.Id
+	}
+	key := name + "/" + c.settings.Baseline
+	if learned, ok := c.baselines[key]; ok {
+		c.learnedBaseline = learned
+	} else {
+		c.baselines[key] = c.learnedBaseline
+	}
+
+	for _, bolt := range c.topology.Bolts {
+		if _, ok := c.learnedBaseline[bolt.Name]; ok {
+			continue
+		}
+		replicas := bolt.Replicas
+		for _, summaryBolt := range summaryTopology.Bolts {
+			if summaryBolt.BoltID == bolt.Name && summaryBolt.Executors > 0 {
+				replicas = summaryBolt.Executors
+			}
+		}
+		c.learnedBaseline[bolt.Name] = replicas
+		c.baselineChanged = true
+	}
+	for _, bolt := range c.topology.Bolts {
+		log.Printf("baseline: topology={%s},mode={%s},bolt={%s},replicas={%d}\n", name, c.settings.Baseline, bolt.Name, c.learnedBaseline[bolt.Name])
+	}
+	c.saveBaseline()
+}
+
+// learnBaseline raises the baseline of the bolt to its replicas, with storm.adaptive.baseline max.
+func (c *Controller) learnBaseline(bolt storm.Bolt) {
+	if c.settings.Baseline != "max" {
+		return
+	}
+	if replicas, ok := c.learnedBaseline[bolt.Name]; !ok || bolt.Replicas > replicas {
+		c.learnedBaseline[bolt.Name] = bolt.Replicas
+		c.baselineChanged = true
+	}
+}
+
+// saveBaseline writes the learned baseline to storm.adaptive.baseline_file when it changed.
+func (c *Controller) saveBaseline() {
+	if !c.baselineChanged {
+		return
+	}
+	if data, err := json.MarshalIndent(c.baselines, "", "  "); err != nil {
+		log.Printf("baseline: error={%v}\n", err)
+	} else if err := os.WriteFile(viper.GetString("storm.adaptive.baseline_file"), data, 0644); err != nil {
+		log.Printf("baseline: error={%v}\n", err)
+	} else {
+		c.baselineChanged = false
+	}
+}
@@ -0,0 +1,61 @@
+package adaptive
+
+import (
+	"sync"
+
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/spf13/viper"
+)
+
+// Collection is what the monitor saw in a time window: the metrics read from the cluster and the topology updated
+// from them, with its derived signals (input rate, latency, degradation, state...).
+type Collection struct {
+	Sequence int64
+	Period   int
+	Metrics  storm.TopologyMetrics
+	Topology storm.Topology
+}
+
+// collectState holds the collectors of a Controller
+type collectState struct {
+	// collectors receive every Collection, in order. Unlike the subscribers of the snapshots, a collector that falls
+	// control_api.collector_buffer collections behind is disconnected, its channel closed, instead of missing some,
+	// so a collector never sees a gap
+	collectors     map[chan Collection]struct{}
+	collectorsLock sync.Mutex
+	// collected is the sequence of the last Collection
+	collected int64
+}
+
+func (c *Controller) publishCollection(metrics storm.TopologyMetrics, topology storm.Topology) {
+	topology = topology.Clone()
+	c.collectorsLock.Lock()
+	defer c.collectorsLock.Unlock()
+	c.collected++
+	collection := Collection{Sequence: c.collected, Period: c.period, Metrics: metrics, Topology: topology}
+	for collector := range c.collectors {
+		select {
+		case collector <- collection:
+		default:
+			delete(c.collectors, collector)
+			close(collector)
+		}
+	}
+}
+
+// Collect returns a channel that receives every Collection from the next window, and the function that closes it.
+// The channel is closed as well when the collector falls behind.
+func (c *Controller) Collect() (<-chan Collection, func()) {
+	collector := make(chan Collection, viper.GetInt("control_api.collector_buffer"))
+	c.collectorsLock.Lock()
+	defer c.collectorsLock.Unlock()
+	c.collectors[collector] = struct{}{}
+	return collector, func() {
+		c.collectorsLock.Lock()
+		defer c.collectorsLock.Unlock()
+		if _, ok := c.collectors[collector]; ok {
+			delete(c.collectors, collector)
+			close(collector)
+		}
+	}
+}
@@ -2,29 +2,82 @@ package adaptive
 
 import (
 	"github.com/dwladdimiroc/sps-storm/internal/storm"
-	"github.com/dwladdimiroc/sps-storm/internal/util"
 	"log"
-	"strconv"
 )
 
-func execute(topology storm.Topology) {
-	if err := updateReplicas(topology); err != nil {
+// executeState holds the replicas that a Controller set in the cluster
+type executeState struct {
+	// applied are the replicas of each bolt last set in the cluster, so a plan only updates the bolts it changes
+	applied map[string]int64
+}
+
+// execute sets in the cluster the replicas planned in the window, and watches the rebalance.
+func (c *Controller) execute(topology storm.Topology, window int) {
+	err := c.updateReplicas(topology)
+	if err != nil {
 		log.Printf("execute: rebalanced topology {%v}\n", err)
 	}
+	c.checkRebalance(err)
+	if err == nil && !c.settings.DryRun {
+		c.watchRebalance(topology, window)
+	}
 	//else {
 	//	log.Printf("execute: rebalanced topology {ok}\n")
 	//}
 }
 
-func updateReplicas(topology storm.Topology) error {
-	var err error
+// revert updates the replicas of the topology on shutdown. Unlike execute, it neither watches the rebalance nor
+// counts its failure towards the safe mode, and a watch still running records nothing.
+func (c *Controller) revert(topology storm.Topology) {
+	c.watchGeneration++
+	c.pendingBolts = nil
+	if err := c.updateReplicas(topology); err != nil {
+		log.Printf("shutdown: rebalanced topology {%v}\n", err)
+	}
+}
+
+// updateReplicas sets in the cluster the replicas of the bolts that changed since they were last set. A
+// BatchCluster receives them in a single update.
+func (c *Controller) updateReplicas(topology storm.Topology) error {
+	changed := make(map[string]int64)
+	var bolts []string
 	for _, bolt := range topology.Bolts {
-		value := strconv.FormatInt(bolt.Replicas, 10)
-		if errRedis := util.RedisSet(bolt.Name, value); errRedis != nil {
-			log.Printf("update replicas error: %v\n", errRedis)
-			err = errRedis
+		if c.settings.Bolt(bolt.Name).Exclude {
+			continue
+		}
+		if applied, ok := c.applied[bolt.Name]; ok && applied == bolt.Replicas {
+			continue
 		}
+		if c.settings.DryRun {
+			log.Printf("dry-run: update replicas bolt={%s},replicas={%d}\n", bolt.Name, bolt.Replicas)
+			continue
+		}
+		changed[bolt.Name] = bolt.Replicas
+		bolts = append(bolts, bolt.Name)
+	}
+	if len(changed) == 0 {
+		return nil
 	}
 
+	if batch, ok := c.cluster.(storm.BatchCluster); ok {
+		err := batch.SetReplicasBatch(changed)
+		if err != nil {
+			log.Printf("update replicas error: %v\n", err)
+			return err
+		}
+		for bolt, replicas := range changed {
+			c.applied[bolt] = replicas
+		}
+		return nil
+	}
+	var err error
+	for _, bolt := range bolts {
+		if errSet := c.cluster.SetReplicas(bolt, changed[bolt]); errSet != nil {
+			log.Printf("update replicas error: %v\n", errSet)
+			err = errSet
+		} else {
+			c.applied[bolt] = changed[bolt]
+		}
+	}
 	return err
 }
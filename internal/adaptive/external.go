@@ -0,0 +1,91 @@
+package adaptive
+
+import (
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/spf13/viper"
+	"log"
+	"sync"
+)
+
+// planState holds the plan submitted to a Controller by an external planner
+type planState struct {
+	// plan is the last plan submitted by an external planner, applied at the end of the next cycle. A plan
+	// submitted before the previous one is applied replaces it.
+	plan     map[string]int64
+	planLock sync.Mutex
+}
+
+// SubmitPlan submits the replicas of the bolts decided by an external planner, when storm.adaptive.planner is
+// external. The replicas go through the same limits, scaling step and cooldown as the internal planner.
+func (c *Controller) SubmitPlan(replicas map[string]int64) error {
+	if viper.GetString("storm.adaptive.planner") != "external" {
+		return fmt.Errorf("storm.adaptive.planner is not external")
+	}
+	for bolt, r := range replicas {
+		if r < 1 {
+			return fmt.Errorf("bolt %s: replicas must be positive, got %d", bolt, r)
+		}
+	}
+	c.planLock.Lock()
+	defer c.planLock.Unlock()
+	c.plan = replicas
+	return nil
+}
+
+func (c *Controller) takePlan() (map[string]int64, bool) {
+	c.planLock.Lock()
+	defer c.planLock.Unlock()
+	submitted := c.plan
+	c.plan = nil
+	return submitted, submitted != nil
+}
+
+// externalPlanning applies the plan submitted by the external planner, if any, to the bolts of the topology. While
+// the state of the topology does not allow plans, the plan is kept for a later window.
+func (c *Controller) externalPlanning(topology *storm.Topology, window int) {
+	// the plan waits for a state that allows it
+	if !State(topology.State).allowsPlanning() {
+		return
+	}
+	if submitted, ok := c.takePlan(); ok {
+		log.Printf("[t=%d] planning: external plan,bolts={%d}\n", window, len(submitted))
+		for bolt := range submitted {
+			if !hasBolt(*topology, bolt) {
+				log.Printf("planning: external plan,unknown bolt={%s}\n", bolt)
+			}
+		}
+		profile := c.settings.Profile(util.Now())
+		topology.Profile = profile.Name
+		for i := range topology.Bolts {
+			if replicas, ok := submitted[topology.Bolts[i].Name]; ok {
+				topology.Bolts[i].PredictionReplicas = replicas
+			} else {
+				topology.Bolts[i].PredictionReplicas = topology.Bolts[i].Replicas
+			}
+		}
+		c.planning(topology, profile, window)
+		if c.deferredPlan {
+			c.restorePlan(submitted)
+		}
+	}
+}
+
+// restorePlan keeps a plan whose planning was deferred for the next window, unless a new plan was submitted.
+func (c *Controller) restorePlan(submitted map[string]int64) {
+	c.planLock.Lock()
+	defer c.planLock.Unlock()
+	if c.plan == nil {
+		c.plan = submitted
+	}
+}
+
+func hasBolt(topology storm.Topology, name string) bool {
+	for _, bolt := range topology.Bolts {
+		if bolt.Name == name {
+			return true
+		}
+	}
+	return false
+}
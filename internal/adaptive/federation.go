@@ -0,0 +1,103 @@
+package adaptive
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/spf13/viper"
+)
+
+// The controllers of topologies in several clusters that share a sink or a budget form a federation through Redis,
+// with features.federation. Each member publishes its replicas every window, under federation.prefix, and it only
+// scales up within federation.replica_budget minus the replicas of the other members. With federation.stagger,
+// a member rebalances only after taking a slot that no other member held in the last federation.stagger ms.
+// When Redis fails, the member plans on its own.
+
+func (c *Controller) federationMember() string {
+	if member := viper.GetString("federation.member"); member != "" {
+		return member
+	}
+	return c.topology.Id
+}
+
+// publishReplicas publishes the replicas of the bolts of the topology to the other members.
+func (c *Controller) publishReplicas(topology storm.Topology) {
+	if !c.settings.Federation {
+		return
+	}
+	var replicas int64
+	for _, bolt := range topology.Bolts {
+		replicas += bolt.Replicas
+	}
+	key := viper.GetString("federation.prefix") + "replicas." + c.federationMember()
+	ttl := time.Duration(viper.GetInt("federation.ttl")) * time.Millisecond
+	if err := util.RedisSetTTL(key, strconv.FormatInt(replicas, 10), ttl); err != nil {
+		log.Printf("federation: publish error={%v}\n", err)
+	}
+}
+
+// federatedBudget returns the replicas left to the topology in the budget of the federation, and false when the
+// budget is unlimited or unknown.
+func (c *Controller) federatedBudget(topology storm.Topology) (int64, bool) {
+	if !c.settings.Federation || c.settings.ReplicaBudget <= 0 {
+		return 0, false
+	}
+	prefix := viper.GetString("federation.prefix") + "replicas."
+	members, err := util.RedisScan(prefix + "*")
+	if err != nil {
+		log.Printf("federation: budget error={%v}\n", err)
+		return 0, false
+	}
+	budget := c.settings.ReplicaBudget
+	for key, value := range members {
+		if key == prefix+c.federationMember() {
+			continue
+		}
+		if replicas, err := strconv.ParseInt(value, 10, 64); err != nil {
+			log.Printf("federation: member={%s},error={%v}\n", key, err)
+		} else {
+			budget -= replicas
+		}
+	}
+	for _, bolt := range topology.Bolts {
+		budget -= bolt.Replicas
+	}
+	if budget < 0 {
+		budget = 0
+	}
+	return budget, true
+}
+
+// limitFederated limits the scale ups of the planned replicas to the budget left, in the order of the bolts.
+func (c *Controller) limitFederated(topology storm.Topology, planned []int64) {
+	budget, ok := c.federatedBudget(topology)
+	if !ok {
+		return
+	}
+	for i, bolt := range topology.Bolts {
+		if up := planned[i] - bolt.Replicas; up > budget {
+			planned[i] = bolt.Replicas + budget
+			log.Printf("federation: budget bolt={%s},replicas={%d}\n", bolt.Name, planned[i])
+		}
+		if planned[i] > bolt.Replicas {
+			budget -= planned[i] - bolt.Replicas
+		}
+	}
+}
+
+// takeRebalanceSlot reports whether the member may rebalance now, taking the slot of federation.stagger ms.
+func (c *Controller) takeRebalanceSlot() bool {
+	if !c.settings.Federation || c.settings.Stagger <= 0 {
+		return true
+	}
+	key := viper.GetString("federation.prefix") + "rebalance"
+	ok, err := util.RedisSetNX(key, c.federationMember(), time.Duration(c.settings.Stagger)*time.Millisecond)
+	if err != nil {
+		log.Printf("federation: stagger error={%v}\n", err)
+		return true
+	}
+	return ok
+}
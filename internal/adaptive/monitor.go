@@ -1,23 +1,28 @@
 package adaptive
 
 import (
-	"github.com/dwladdimiroc/sps-storm/internal/predictive"
+	"github.com/dwladdimiroc/sps-storm/internal/nats"
 	"github.com/dwladdimiroc/sps-storm/internal/storm"
 	"github.com/dwladdimiroc/sps-storm/internal/util"
-	"github.com/spf13/viper"
 	"log"
+	"math"
 	"strconv"
 )
 
-func monitor(topology *storm.Topology) bool {
-	if ok, topologyMetrics := storm.GetMetrics(*topology); ok {
-		log.Printf("[t=%d] monitor: update stats topology\n", period*viper.GetInt("storm.adaptive.time_window_size"))
-		updateTopology(topology, topologyMetrics)
-		saveMetrics(*topology)
-		period++
-		if !topology.Benchmark && period == viper.GetInt("storm.adaptive.benchmark_samples") {
+func (c *Controller) monitor(topology *storm.Topology) bool {
+	if ok, topologyMetrics := c.cluster.GetMetrics(*topology); ok {
+		log.Printf("[t=%d] monitor: update stats topology\n", c.period*int(c.settings.TimeWindowSize))
+		c.updateTopology(topology, topologyMetrics)
+		c.updateSummary(*topology)
+		c.saveMetrics(*topology)
+		c.period++
+		if !topology.Benchmark && c.period == c.settings.BenchmarkSamples {
 			topology.BenchmarkExecutedTimeAvg()
 		}
+		c.updateState(topology)
+		c.checkDegraded(topology)
+		c.publishCollection(topologyMetrics, *topology)
+		c.publishReplicas(*topology)
 		return ok
 	} else {
 		log.Printf("monitor: error get metric")
@@ -25,15 +30,19 @@ func monitor(topology *storm.Topology) bool {
 	}
 }
 
-func updateTopology(topology *storm.Topology, metrics storm.TopologyMetrics) {
-	updateStatsInputStream(topology, metrics)
-	updateStatsBolt(topology, metrics)
-	updateLatency(topology)
-	updatePredictedInput(topology)
+func (c *Controller) updateTopology(topology *storm.Topology, metrics storm.TopologyMetrics) {
+	c.updateStatsInputStream(topology, metrics)
+	c.updateStatsBolt(topology, metrics)
+	c.updateLatency(topology, metrics)
+	c.updateLag(topology)
+	c.updatePredictedInput(topology)
+	c.updateThroughput(topology)
+	c.updateAcked(topology, metrics)
+	c.updateSlaScore(topology)
+	c.updateCost(topology)
 }
 
-func updateStatsInputStream(topology *storm.Topology, metrics storm.TopologyMetrics) {
-	var inputRate int64
+func (c *Controller) updateStatsInputStream(topology *storm.Topology, metrics storm.TopologyMetrics) {
 	for _, spout := range metrics.Spouts {
 		for _, outputStat := range spout.OutputStats {
 			for i := range topology.Bolts {
@@ -43,11 +52,6 @@ func updateStatsInputStream(topology *storm.Topology, metrics storm.TopologyMetr
 
 			}
 		}
-		for _, stats := range spout.SpoutSummary {
-			if stats.Window == ":all-time" {
-				inputRate += int64(stats.Emitted)
-			}
-		}
 	}
 
 	for i := range topology.Bolts {
@@ -57,43 +61,123 @@ func updateStatsInputStream(topology *storm.Topology, metrics storm.TopologyMetr
 				topology.Bolts[i].InputTotal = topology.Bolts[i].Input
 				topology.Bolts[i].Input = inputBoltCurrent
 			} else {
-				topology.Bolts[i].InputTotal += topology.InputRate[len(topology.InputRate)-1]
-				topology.Bolts[i].Input = topology.InputRate[len(topology.InputRate)-1]
+				topology.Bolts[i].InputTotal += topology.InputRate.Last()
+				topology.Bolts[i].Input = topology.InputRate.Last()
 			}
 		}
 	}
 
+	inputRate, err := c.inputRateSource.Total(metrics)
+	if err != nil {
+		// the count of the spouts is not the count of the source, so the window repeats the last input rate and
+		// the accumulated count of the source is kept for its next answer
+		log.Printf("monitor: %s input rate error={%v}\n", c.settings.InputRateSource, err)
+		if topology.InputRate.Len() > 0 {
+			topology.InputRateT = topology.InputRate.Last()
+			topology.InputRate.Append(topology.InputRate.Last())
+		}
+		return
+	}
+
 	inputRateCurrent := inputRate - topology.InputRateAccum // difference between inputRate_{t} and inputRate_{t-1}
 	topology.InputRateAccum = inputRate
 	if topology.InputRateAccum > 0 {
-		topology.InputRate = append(topology.InputRate, inputRateCurrent)
+		topology.InputRateT = inputRateCurrent
+		topology.InputRate.Append(c.smoothInputRate(topology, inputRateCurrent))
+	} else {
+		if topology.InputRate.Len() > 0 {
+			topology.InputRateT = topology.InputRate.Last()
+			topology.InputRate.Append(topology.InputRate.Last())
+		}
+	}
+	//log.Printf("[monitor] period={%d},inputRate={%d}", period, topology.InputRate.Last())
+}
+
+// smoothInputRate returns the input rate of the window smoothed with an EWMA of factor
+// storm.adaptive.input_rate_smoothing, which is the input rate itself when the factor is 0.
+func (c *Controller) smoothInputRate(topology *storm.Topology, inputRate int64) int64 {
+	alpha := c.settings.InputRateSmoothing
+	if alpha <= 0 || alpha >= 1 || topology.InputRate.Len() == 0 {
+		topology.InputRateSmooth = float64(inputRate)
 	} else {
-		if len(topology.InputRate) > 0 {
-			topology.InputRate = append(topology.InputRate, topology.InputRate[len(topology.InputRate)-1])
+		topology.InputRateSmooth = alpha*float64(inputRate) + (1-alpha)*topology.InputRateSmooth
+	}
+	return int64(math.Round(topology.InputRateSmooth))
+}
+
+func (c *Controller) updateLatency(topology *storm.Topology, metrics storm.TopologyMetrics) {
+	topology.Time = int64(c.period) * c.settings.TimeWindowSize
+	if c.settings.LatencySource == "complete" {
+		// with no acks in the window the latency of the previous one is kept
+		if latency, ok := c.completeLatency(metrics); ok {
+			topology.Latency = latency
 		}
+	} else {
+		topology.Latency = c.cluster.GetLatency()
 	}
-	//log.Printf("[monitor] period={%d},inputRate={%d}", period, topology.InputRate[len(topology.InputRate)-1])
 }
 
-func updateLatency(topology *storm.Topology) {
-	topology.Time = int64(period) * viper.GetInt64("storm.adaptive.time_window_size")
-	topology.Latency = util.GetLatency()
+// spoutLatency is the all-time acked tuples of a spout and their complete latency, in the previous window
+type spoutLatency struct {
+	acked   int64
+	latency float64
+}
+
+// monitorState holds the state of the monitor of a Controller between windows
+type monitorState struct {
+	// spoutLatencies are the spoutLatency of each spout, by name
+	spoutLatencies map[string]spoutLatency
 }
 
-func updateStatsBolt(topology *storm.Topology, metrics storm.TopologyMetrics) {
+// completeLatency returns the complete latency of the tuples acked by the spouts in the window, from the end of
+// their emission to the ack of their whole tree, mean over the spouts weighted by their acks. Since the complete
+// latency of Storm is the mean since the start, the latency of the window follows from the growth of the sum of
+// the latencies. It is false when no tuple was acked in the window.
+func (c *Controller) completeLatency(metrics storm.TopologyMetrics) (float64, bool) {
+	var acked int64
+	var latency float64
+	for _, spout := range metrics.Spouts {
+		for _, stats := range spout.SpoutSummary {
+			if stats.Window != ":all-time" {
+				continue
+			}
+			previous := c.spoutLatencies[spout.Id]
+			current := spoutLatency{acked: int64(stats.Acked), latency: stats.CompleteLatency}
+			c.spoutLatencies[spout.Id] = current
+			if current.acked < previous.acked {
+				// the counter was reset, so the mean starts again
+				previous = spoutLatency{}
+			}
+			if delta := current.acked - previous.acked; delta > 0 {
+				acked += delta
+				latency += float64(current.acked)*current.latency - float64(previous.acked)*previous.latency
+			}
+		}
+	}
+	if acked == 0 {
+		return 0, false
+	}
+	return latency / float64(acked), true
+}
+
+func (c *Controller) updateStatsBolt(topology *storm.Topology, metrics storm.TopologyMetrics) {
 	for _, bolt := range metrics.Bolts {
 		updateOutputBolt(topology, bolt)
-		updateExecutedAvg(topology, bolt)
+		c.updateExecutedAvg(topology, bolt)
+		updateGpu(topology, bolt)
 	}
 
 	for i := range topology.Bolts {
-		topology.Bolts[i].Time = int64(period) * viper.GetInt64("storm.adaptive.time_window_size")
+		topology.Bolts[i].Time = int64(c.period) * c.settings.TimeWindowSize
 		updateInputBolt(&topology.Bolts[i], metrics)
 	}
 
 	for i := range topology.Bolts {
 		updateQueue(&topology.Bolts[i])
 	}
+	for _, bolt := range metrics.Bolts {
+		updateBacklog(topology, bolt)
+	}
 }
 
 func updateOutputBolt(topology *storm.Topology, boltMetrics storm.BoltMetrics) {
@@ -104,14 +188,14 @@ func updateOutputBolt(topology *storm.Topology, boltMetrics storm.BoltMetrics) {
 					topology.Bolts[i].Output = boltStats.Executed
 				}
 			}
-			outputBoltCurrent := topology.Bolts[i].Output - topology.Bolts[i].ExecutedTotal
+			outputBoltCurrent := counterDelta(topology.Bolts[i].Output, topology.Bolts[i].ExecutedTotal)
 			topology.Bolts[i].ExecutedTotal = topology.Bolts[i].Output
 			topology.Bolts[i].Output = outputBoltCurrent
 		}
 	}
 }
 
-func updateExecutedAvg(topology *storm.Topology, boltMetrics storm.BoltMetrics) {
+func (c *Controller) updateExecutedAvg(topology *storm.Topology, boltMetrics storm.BoltMetrics) {
 	for i := range topology.Bolts {
 		if topology.Bolts[i].Name == boltMetrics.Id {
 			for _, boltStats := range boltMetrics.BoltStats {
@@ -121,14 +205,27 @@ func updateExecutedAvg(topology *storm.Topology, boltMetrics storm.BoltMetrics)
 				}
 			}
 
-			topology.Bolts[i].ExecutedTimeAvgSamples = append(topology.Bolts[i].ExecutedTimeAvgSamples, topology.Bolts[i].ExecutedTimeAvg)
+			if c.inRebalanceBlackout(topology.Bolts[i]) {
+				log.Printf("[t=%d] monitor: rebalance blackout bolt={%s}\n", c.period*int(c.settings.TimeWindowSize), topology.Bolts[i].Name)
+				continue
+			}
+			topology.Bolts[i].ExecutedTimeAvgSamples.Push(topology.Bolts[i].ExecutedTimeAvg)
 			if !topology.Benchmark {
-				topology.Bolts[i].ExecutedTimeBenchmarkAvgSamples = append(topology.Bolts[i].ExecutedTimeBenchmarkAvgSamples, topology.Bolts[i].ExecutedTimeAvg)
+				topology.Bolts[i].ExecutedTimeBenchmarkAvgSamples.Push(topology.Bolts[i].ExecutedTimeAvg)
 			}
 		}
 	}
 }
 
+func updateGpu(topology *storm.Topology, boltMetrics storm.BoltMetrics) {
+	for i := range topology.Bolts {
+		if topology.Bolts[i].Name == boltMetrics.Id {
+			topology.Bolts[i].GpuUtilization = boltMetrics.GpuUtilization
+			topology.Bolts[i].GpuMemory = boltMetrics.GpuMemory
+		}
+	}
+}
+
 func updateInputBolt(bolt *storm.Bolt, topologyMetrics storm.TopologyMetrics) {
 	var inputBolt int64
 	for _, boltMetrics := range topologyMetrics.Bolts {
@@ -140,7 +237,7 @@ func updateInputBolt(bolt *storm.Bolt, topologyMetrics storm.TopologyMetrics) {
 	}
 
 	if inputBolt > 0 {
-		inputBoltCurrent := inputBolt - bolt.InputTotal
+		inputBoltCurrent := counterDelta(inputBolt, bolt.InputTotal)
 		bolt.InputTotal = inputBolt
 		bolt.Input = inputBoltCurrent
 	}
@@ -152,16 +249,93 @@ func updateQueue(bolt *storm.Bolt) {
 	}
 }
 
-func updatePredictedInput(topology *storm.Topology) {
-	topology.InputRateT = topology.InputRate[period]
+// updateBacklog sets the queue of the bolt to the backlog reported by the cluster, when it reports one.
+func updateBacklog(topology *storm.Topology, boltMetrics storm.BoltMetrics) {
+	if boltMetrics.Backlog == nil {
+		return
+	}
+	for i := range topology.Bolts {
+		if topology.Bolts[i].Name == boltMetrics.Id {
+			topology.Bolts[i].Queue = *boltMetrics.Backlog
+		}
+	}
+}
+
+// updateLag reads the consumer lag of the JetStream consumer of the spouts, when nats.consumer is set. On error,
+// the lag of the previous window is kept.
+func (c *Controller) updateLag(topology *storm.Topology) {
+	if c.settings.NatsConsumer == "" {
+		return
+	}
+	if lag, err := nats.Lag(); err != nil {
+		log.Printf("monitor: nats lag error={%v}\n", err)
+	} else {
+		topology.Lag = lag
+	}
+}
 
-	if len(topology.PredictedInputRate) > 0 {
-		topology.PredictModel = predictive.GetPred().NameModel
-		topology.PredictedInputRateT = topology.PredictedInputRate[period]
+func (c *Controller) updatePredictedInput(topology *storm.Topology) {
+	if c.period < len(topology.PredictedInputRate) {
+		topology.PredictModel = c.predictor.Get().NameModel
+		topology.PredictedInputRateT = topology.PredictedInputRate[c.period]
 	}
 }
 
-func saveMetrics(topology storm.Topology) {
+// updateThroughput sets the tuples executed by the output bolts in the window, and the degradation of the
+// throughput: the fraction of the input rate that did not reach them.
+func (c *Controller) updateThroughput(topology *storm.Topology) {
+	topology.Throughput = 0
+	for _, bolt := range topology.Bolts {
+		for _, name := range c.outputBolts {
+			if bolt.Name == name {
+				topology.Throughput += bolt.Output
+			}
+		}
+	}
+
+	topology.Degradation = 0
+	if inputRate := topology.InputRate.Last(); inputRate > 0 {
+		if degradation := 1 - float64(topology.Throughput)/float64(inputRate); degradation > 0 {
+			topology.Degradation = degradation
+		}
+	}
+}
+
+// updateAcked sets the tuples acked by the spouts in the window. With storm.adaptive.degradation acked, the
+// degradation is the fraction of the tuples emitted by the spouts that were not acked, so the failed and the
+// timed out tuples count as degraded.
+func (c *Controller) updateAcked(topology *storm.Topology, metrics storm.TopologyMetrics) {
+	var emitted, acked int64
+	for _, spout := range metrics.Spouts {
+		for _, stats := range spout.SpoutSummary {
+			if stats.Window == ":all-time" {
+				emitted += int64(stats.Emitted)
+				acked += int64(stats.Acked)
+			}
+		}
+	}
+	// a spout whose counters were reset counts again from zero, as the sources of the input rate
+	emittedCurrent := counterDelta(emitted, topology.EmittedAccum)
+	topology.Acked = counterDelta(acked, topology.AckedAccum)
+	topology.EmittedAccum = emitted
+	topology.AckedAccum = acked
+
+	if c.settings.Degradation != "acked" {
+		return
+	}
+	topology.Degradation = 0
+	if emittedCurrent > 0 {
+		if degradation := 1 - float64(topology.Acked)/float64(emittedCurrent); degradation > 0 {
+			topology.Degradation = degradation
+		}
+	}
+}
+
+func (c *Controller) saveMetrics(topology storm.Topology) {
+	if !c.settings.Exporters {
+		return
+	}
+
 	for _, bolt := range topology.Bolts {
 		if err := util.WriteCsv(topology.Id, bolt.Name, []storm.Bolt{bolt}); err != nil {
 			log.Printf("error write csv: %v\n", err)
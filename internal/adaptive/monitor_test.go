@@ -0,0 +1,39 @@
+package adaptive
+
+import (
+	"testing"
+
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+)
+
+func spoutCounters(emitted, acked int) storm.TopologyMetrics {
+	return storm.TopologyMetrics{Spouts: []storm.SpoutMetrics{{
+		Id:           "spout",
+		SpoutSummary: []storm.SpoutSummary{{Emitted: emitted, Acked: acked, Window: ":all-time"}},
+	}}}
+}
+
+// A worker restarted by the cluster resets the counters of its spout, which count again from zero.
+func TestUpdateAckedCounterReset(t *testing.T) {
+	c := &Controller{settings: util.Settings{Degradation: "acked"}}
+	var topology storm.Topology
+
+	c.updateAcked(&topology, spoutCounters(1000, 750))
+	if topology.Acked != 750 || topology.Degradation != 0.25 {
+		t.Fatalf("before the reset: acked=%d,degradation=%v", topology.Acked, topology.Degradation)
+	}
+
+	c.updateAcked(&topology, spoutCounters(200, 150))
+	if topology.Acked != 150 {
+		t.Errorf("after the reset: acked=%d, want 150", topology.Acked)
+	}
+	if topology.Degradation != 0.25 {
+		t.Errorf("after the reset: degradation=%v, want 0.25", topology.Degradation)
+	}
+
+	c.updateAcked(&topology, spoutCounters(300, 250))
+	if topology.Acked != 100 || topology.Degradation != 0 {
+		t.Errorf("after the reset: acked=%d,degradation=%v", topology.Acked, topology.Degradation)
+	}
+}
@@ -0,0 +1,83 @@
+package adaptive
+
+import (
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"log"
+	"sync"
+)
+
+// pipelineState holds the analyze stage of a Controller. With storm.adaptive.pipeline, the analysis runs in its own
+// stage, fed by the monitor through the channel analyzeTicks with the period of each window. The forecast is made
+// without the lock of the cycle, so a slow predictor only delays the planning while the monitor keeps closing
+// windows. The stage analyzes, plans and executes the window of its tick, so the plan and the execution stay
+// inline: they change the replicas that the monitor reads, and must run under the lock of the cycle right after
+// the analysis of the same window.
+//
+// The monitor never waits for the stage. The channel holds one tick and, while it is full, the tick of a new
+// window is shed: the window waiting is analyzed, and the analysis of the new one is left to the next tick, since
+// the samples of both are already in the topology.
+type pipelineState struct {
+	analyzeTicks chan int
+	analyzeStage sync.WaitGroup
+}
+
+func (c *Controller) startPipeline(topology *storm.Topology) {
+	c.analyzeTicks = make(chan int, 1)
+	c.analyzeStage.Add(1)
+	go func() {
+		defer c.analyzeStage.Done()
+		for tick := range c.analyzeTicks {
+			c.analyzePipeline(topology, tick)
+		}
+	}()
+}
+
+// sendAnalyze sends the period of the window to the analyze stage, or sheds it when the stage has a window waiting.
+func (c *Controller) sendAnalyze(tick int) {
+	select {
+	case c.analyzeTicks <- tick:
+	default:
+		log.Printf("[t=%d] analyze: stage busy, window shed\n", tick)
+	}
+}
+
+// stopPipeline waits for the analysis in progress. The channel is detached under the lock of the cycle, so a
+// cycle still running does not send to it once it is closed.
+func (c *Controller) stopPipeline() {
+	c.cycle.Lock()
+	ticks := c.analyzeTicks
+	c.analyzeTicks = nil
+	c.cycle.Unlock()
+	if ticks != nil {
+		close(ticks)
+		c.analyzeStage.Wait()
+	}
+}
+
+func (c *Controller) analyzePipeline(topology *storm.Topology, tick int) {
+	if c.predictionPeriod(tick) {
+		c.cycle.Lock()
+		samples := c.predictor.Samples(topology)
+		c.cycle.Unlock()
+
+		forecast := c.predictor.Forecast(samples)
+
+		c.cycle.Lock()
+		if !c.stopped {
+			c.predictInput(topology, forecast, tick)
+		}
+		c.cycle.Unlock()
+	}
+
+	c.cycle.Lock()
+	defer c.cycle.Unlock()
+	if c.stopped {
+		return
+	}
+	if c.planningPeriod(tick) || c.deferredPlan {
+		c.determinateReplicas(topology, tick)
+	}
+	if c.settings.Planner == "external" {
+		c.externalPlanning(topology, tick)
+	}
+}
@@ -2,23 +2,151 @@ package adaptive
 
 import (
 	"github.com/dwladdimiroc/sps-storm/internal/storm"
-	"github.com/spf13/viper"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
 	"log"
 )
 
-func planning(topology *storm.Topology) {
+// planning plans the replicas of the bolts in the window of the given period, and executes the plan.
+func (c *Controller) planning(topology *storm.Topology, profile util.Profile, window int) {
+	state := State(topology.State)
+	if !state.allowsPlanning() {
+		log.Printf("[t=%d] planning: deferred,state={%s}\n", window, state)
+		c.deferredPlan = state.defersPlanning()
+		return
+	}
+	c.deferredPlan = false
+	planned := make([]int64, len(topology.Bolts))
 	for i := range topology.Bolts {
-		if topology.Bolts[i].PredictionReplicas < 1 {
-			topology.Bolts[i].Replicas = 1
-		} else {
-			if topology.Bolts[i].PredictionReplicas > viper.GetInt64("storm.adaptive.limit_replicas") {
-				topology.Bolts[i].Replicas = viper.GetInt64("storm.adaptive.limit_replicas")
-			} else {
-				topology.Bolts[i].Replicas = topology.Bolts[i].PredictionReplicas
-			}
+		planned[i] = c.plannedReplicas(topology.Bolts[i], profile, state, window)
+	}
+	c.limitGpus(*topology, planned)
+	c.limitFederated(*topology, planned)
+	if changesReplicas(*topology, planned) && !c.takeRebalanceSlot() {
+		log.Printf("[t=%d] planning: deferred,federation={stagger}\n", window)
+		c.deferredPlan = true
+		return
+	}
+	for i := range topology.Bolts {
+		if c.settings.Bolt(topology.Bolts[i].Name).Exclude {
+			continue
 		}
+		replicas := planned[i]
+		if replicas > topology.Bolts[i].Replicas {
+			topology.Bolts[i].ScaleUpPeriod = window
+		}
+		if replicas != topology.Bolts[i].Replicas {
+			topology.Bolts[i].RebalancePeriod = window
+		}
+		topology.Bolts[i].Replicas = replicas
+		c.learnBaseline(topology.Bolts[i])
+		c.appendDecision(Decision{Period: window, Profile: profile.Name, Bolt: topology.Bolts[i].Name,
+			PredictionReplicas: topology.Bolts[i].PredictionReplicas, Replicas: replicas, Issued: util.Now()})
 		log.Printf("planning: ok\n")
-		log.Printf("planning: bolt={%s},replicas={%d}\n", topology.Bolts[i].Name, topology.Bolts[i].Replicas)
+		log.Printf("planning: profile={%s},bolt={%s},replicas={%d}\n", profile.Name, topology.Bolts[i].Name, topology.Bolts[i].Replicas)
+	}
+	c.saveBaseline()
+	c.execute(*topology, window)
+}
+
+// plannedReplicas returns the replicas planned for the bolt from its predicted replicas, within the bounds of the
+// bolt and the profile, its scaling step and its scale-down cooldown. An excluded bolt keeps its replicas.
+func (c *Controller) plannedReplicas(bolt storm.Bolt, profile util.Profile, state State, window int) int64 {
+	boltConfig := c.settings.Bolt(bolt.Name)
+	if boltConfig.Exclude {
+		return bolt.Replicas
+	}
+	if profile.MaxReplicas > 0 && profile.MaxReplicas < boltConfig.MaxReplicas {
+		boltConfig.MaxReplicas = profile.MaxReplicas
+		if boltConfig.MinReplicas > boltConfig.MaxReplicas {
+			boltConfig.MinReplicas = boltConfig.MaxReplicas
+		}
+	}
+	replicas := limitReplicas(bolt.PredictionReplicas, bolt.Replicas, boltConfig)
+	if replicas < bolt.Replicas && (c.inScaleDownCooldown(bolt, profile, window) || !state.allowsScaleDown()) {
+		return bolt.Replicas
+	}
+	return replicas
+}
+
+// limitGpus limits the scale ups of the bolts with gpus to the GPUs of storm.adaptive.gpu_slots left by the
+// current replicas and the scale downs of the plan, in the order of the bolts.
+func (c *Controller) limitGpus(topology storm.Topology, planned []int64) {
+	if c.settings.GpuSlots <= 0 {
+		return
+	}
+	free := c.settings.GpuSlots
+	for i, bolt := range topology.Bolts {
+		gpus := c.settings.Bolt(bolt.Name).Gpus
+		if planned[i] < bolt.Replicas {
+			free -= planned[i] * gpus
+		} else {
+			free -= bolt.Replicas * gpus
+		}
+	}
+	for i, bolt := range topology.Bolts {
+		gpus := c.settings.Bolt(bolt.Name).Gpus
+		if gpus <= 0 || planned[i] <= bolt.Replicas {
+			continue
+		}
+		up := planned[i] - bolt.Replicas
+		if up*gpus > free {
+			up = max(free/gpus, 0)
+			planned[i] = bolt.Replicas + up
+			log.Printf("planning: gpu slots bolt={%s},replicas={%d}\n", bolt.Name, planned[i])
+		}
+		free -= up * gpus
+	}
+}
+
+func changesReplicas(topology storm.Topology, planned []int64) bool {
+	for i, bolt := range topology.Bolts {
+		if planned[i] != bolt.Replicas {
+			return true
+		}
+	}
+	return false
+}
+
+func limitReplicas(replicas int64, current int64, boltConfig util.BoltConfig) int64 {
+	if boltConfig.ScalingStep > 0 {
+		if replicas > current+boltConfig.ScalingStep {
+			replicas = current + boltConfig.ScalingStep
+		} else if replicas < current-boltConfig.ScalingStep {
+			replicas = current - boltConfig.ScalingStep
+		}
+	}
+
+	if replicas < boltConfig.MinReplicas {
+		return boltConfig.MinReplicas
+	} else if replicas > boltConfig.MaxReplicas {
+		return boltConfig.MaxReplicas
+	} else {
+		return replicas
+	}
+}
+
+// inRebalanceBlackout reports whether the rebalance of the bolt is pending, or completed less than
+// rebalance_blackout windows ago, while its workers restart and its executed time does not measure the new
+// replicas.
+func (c *Controller) inRebalanceBlackout(bolt storm.Bolt) bool {
+	return c.pendingBolts[bolt.Name] || bolt.RebalancePeriod > 0 && c.period-bolt.RebalancePeriod < c.settings.RebalanceBlackout
+}
+
+// inScaleDownCooldown reports whether the bolt scaled up less than scale_down_cooldown planning windows ago.
+func (c *Controller) inScaleDownCooldown(bolt storm.Bolt, profile util.Profile, window int) bool {
+	cooldown := profile.ScaleDownCooldown * c.settings.PlanningSamples
+	return bolt.ScaleUpPeriod > 0 && window-bolt.ScaleUpPeriod < cooldown
+}
+
+// baselineReplicas returns the replicas of the bolt in a static provisioning, the baseline_replicas of the
+// profile of the hour with storm.adaptive.baseline config.
+func (c *Controller) baselineReplicas(bolt storm.Bolt) int64 {
+	if replicas, ok := c.learnedBaseline[bolt.Name]; ok && c.settings.Baseline != "config" {
+		return replicas
+	}
+	boltConfig := c.settings.Bolt(bolt.Name)
+	if baseline := c.settings.Profile(util.Now()).BaselineReplicas; baseline > 0 {
+		return limitReplicas(baseline, bolt.Replicas, util.BoltConfig{MinReplicas: boltConfig.MinReplicas, MaxReplicas: boltConfig.MaxReplicas})
 	}
-	execute(*topology)
+	return boltConfig.MinReplicas
 }
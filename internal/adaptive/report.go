@@ -0,0 +1,117 @@
+package adaptive
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+)
+
+// Report is the self-contained record of a time window, written in reports/ of the statistics of the topology
+// with features.reports: the topology at the end of the window, the decisions of its planning, the forecast of
+// the window against its actual input rate, the plan of the replicas and the summary of the run so far.
+type Report struct {
+	Period    int              `json:"period"`
+	Time      time.Time        `json:"time"`
+	Topology  reportTopology   `json:"topology"`
+	Decisions []Decision       `json:"decisions"`
+	Forecast  reportForecast   `json:"forecast"`
+	Plan      map[string]int64 `json:"plan"`
+	Summary   Summary          `json:"summary"`
+}
+
+type reportTopology struct {
+	Id              string       `json:"id"`
+	Profile         string       `json:"profile"`
+	State           string       `json:"state"`
+	InputRate       int64        `json:"input_rate"`
+	InputRateSmooth float64      `json:"input_rate_smooth"`
+	Latency         float64      `json:"latency"`
+	Lag             int64        `json:"lag"`
+	Throughput      int64        `json:"throughput"`
+	Degradation     float64      `json:"degradation"`
+	SlaScore        float64      `json:"sla_score"`
+	Cost            float64      `json:"cost"`
+	Bolts           []reportBolt `json:"bolts"`
+}
+
+type reportBolt struct {
+	Name                     string  `json:"name"`
+	Replicas                 int64   `json:"replicas"`
+	PredictionReplicas       int64   `json:"prediction_replicas"`
+	Input                    int64   `json:"input"`
+	Output                   int64   `json:"output"`
+	Queue                    int64   `json:"queue"`
+	ExecutedTimeAvg          float64 `json:"executed_time_avg"`
+	ExecutedTimeBenchmarkAvg float64 `json:"executed_time_benchmark_avg"`
+}
+
+// reportForecast is the input rate predicted for the window against the actual one, and the input rate predicted
+// for the next planning window
+type reportForecast struct {
+	Model     string  `json:"model"`
+	Predicted int64   `json:"predicted"`
+	Actual    int64   `json:"actual"`
+	Error     int64   `json:"error"`
+	Next      []int64 `json:"next"`
+}
+
+// writeReport writes the Report of the window just analyzed, as reports/<period>.json.
+func (c *Controller) writeReport(topology storm.Topology) {
+	if !c.settings.Reports {
+		return
+	}
+	report := Report{
+		Period:    c.period,
+		Time:      util.Now(),
+		Decisions: []Decision{},
+		Plan:      make(map[string]int64),
+		Summary:   c.summary,
+		Topology: reportTopology{
+			Id:              topology.Id,
+			Profile:         topology.Profile,
+			State:           topology.State,
+			InputRate:       topology.InputRateT,
+			InputRateSmooth: topology.InputRateSmooth,
+			Latency:         topology.Latency,
+			Lag:             topology.Lag,
+			Throughput:      topology.Throughput,
+			Degradation:     topology.Degradation,
+			SlaScore:        topology.SlaScore,
+			Cost:            topology.Cost,
+		},
+		Forecast: reportForecast{
+			Model:     topology.PredictModel,
+			Predicted: topology.PredictedInputRateT,
+			Actual:    topology.InputRateT,
+			Error:     topology.PredictedInputRateT - topology.InputRateT,
+		},
+	}
+	for i := len(c.decisions) - 1; i >= 0 && c.decisions[i].Period == c.period; i-- {
+		report.Decisions = append([]Decision{c.decisions[i]}, report.Decisions...)
+	}
+	if len(c.predictor.Get().PredictedInput) > 0 {
+		for j := 0; j < c.settings.PlanningSamples; j++ {
+			report.Forecast.Next = append(report.Forecast.Next, c.predictor.PredictedInputPeriod(c.period+j))
+		}
+	}
+	for _, bolt := range topology.Bolts {
+		report.Plan[bolt.Name] = bolt.Replicas
+		report.Topology.Bolts = append(report.Topology.Bolts, reportBolt{
+			Name:                     bolt.Name,
+			Replicas:                 bolt.Replicas,
+			PredictionReplicas:       bolt.PredictionReplicas,
+			Input:                    bolt.Input,
+			Output:                   bolt.Output,
+			Queue:                    bolt.Queue,
+			ExecutedTimeAvg:          bolt.ExecutedTimeAvg,
+			ExecutedTimeBenchmarkAvg: bolt.ExecutedTimeBenchmarkAvg,
+		})
+	}
+
+	if err := util.WriteJson(topology.Id, "reports", fmt.Sprintf("%06d", c.period), report); err != nil {
+		log.Printf("error write report: %v\n", err)
+	}
+}
@@ -0,0 +1,107 @@
+package adaptive
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/spf13/viper"
+)
+
+// safeState holds the safe mode of a Controller
+type safeState struct {
+	// safeMode is set when the adaptive system stopped adapting the topology after repeated failures, until an
+	// operator resumes it
+	safeMode bool
+	// degradedWindows and failedRebalances are the consecutive degraded windows and failed rebalances
+	degradedWindows, failedRebalances int
+}
+
+// checkDegraded counts the consecutive degraded windows, and enters the safe mode after
+// storm.adaptive.safe_mode.degraded_windows of them.
+func (c *Controller) checkDegraded(topology *storm.Topology) {
+	if State(topology.State) == Degraded {
+		c.degradedWindows++
+	} else {
+		c.degradedWindows = 0
+	}
+	if n := c.settings.SafeDegraded; n > 0 && c.degradedWindows >= n && !c.safeMode {
+		c.enterSafeMode(topology, fmt.Sprintf("%d consecutive degraded windows", c.degradedWindows))
+	}
+}
+
+// checkRebalance counts the consecutive failed rebalances, and enters the safe mode after
+// storm.adaptive.safe_mode.failed_rebalances of them.
+func (c *Controller) checkRebalance(err error) {
+	if err == nil {
+		c.failedRebalances = 0
+		return
+	}
+	c.failedRebalances++
+	if n := c.settings.SafeRebalances; n > 0 && c.failedRebalances >= n && !c.safeMode {
+		c.enterSafeMode(c.topology, fmt.Sprintf("%d consecutive failed rebalances", c.failedRebalances))
+	}
+}
+
+// enterSafeMode restores the baseline replicas of the bolts and freezes the planning, raising an alert. The
+// restore is executed as a rebalance of the period, so it honours the dry run, starts the blackout and is watched;
+// the safe mode is set before, so a failed restore does not enter it again.
+func (c *Controller) enterSafeMode(topology *storm.Topology, reason string) {
+	c.safeMode = true
+	log.Printf("[t=%d] safe mode: enter,reason={%s}\n", c.period*int(c.settings.TimeWindowSize), reason)
+	topology.State = string(Safe)
+	for i := range topology.Bolts {
+		replicas := c.baselineReplicas(topology.Bolts[i])
+		if replicas != topology.Bolts[i].Replicas {
+			topology.Bolts[i].RebalancePeriod = c.period
+		}
+		topology.Bolts[i].Replicas = replicas
+		log.Printf("safe mode: baseline bolt={%s},replicas={%d}\n", topology.Bolts[i].Name, topology.Bolts[i].Replicas)
+	}
+	c.execute(*topology, c.period)
+	go alert(topology.Id, reason)
+}
+
+// alert posts the reason of the safe mode to storm.adaptive.safe_mode.alert_url, when it is set.
+func alert(topologyId, reason string) {
+	url := viper.GetString("storm.adaptive.safe_mode.alert_url")
+	if url == "" {
+		return
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"topology": topologyId,
+		"event":    "safe_mode",
+		"reason":   reason,
+		"time":     util.Now().Format(time.RFC3339),
+	})
+	client := &http.Client{Timeout: 5 * time.Second}
+	if res, err := client.Post(url, "application/json", bytes.NewBuffer(body)); err != nil {
+		log.Printf("safe mode: alert error={%v}\n", err)
+	} else {
+		if err := res.Body.Close(); err != nil {
+			log.Printf("safe mode: alert error={%v}\n", err)
+		}
+		if res.StatusCode >= 300 {
+			log.Printf("safe mode: alert status={%s}\n", res.Status)
+		}
+	}
+}
+
+// Resume leaves the safe mode, so the adaptive system plans again from the next window.
+func (c *Controller) Resume() error {
+	c.cycle.Lock()
+	defer c.cycle.Unlock()
+	if !c.safeMode {
+		return fmt.Errorf("resume: not in safe mode")
+	}
+	c.safeMode = false
+	c.degradedWindows = 0
+	c.failedRebalances = 0
+	log.Printf("safe mode: resume\n")
+	return nil
+}
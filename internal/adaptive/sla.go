@@ -0,0 +1,57 @@
+package adaptive
+
+import (
+	"math"
+
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+)
+
+// slaTerms are the compliance of the window with each term of the SLA, from 0 (far from it) to 1 (within it),
+// and whether the term is part of the SLA
+type slaTerms struct {
+	latency, degradation, lag float64
+	hasLatency, hasLag        bool
+}
+
+// updateSlaScore sets the compliance of the window with the SLA: the mean of the compliance of its terms, weighted
+// by storm.adaptive.sla_score.weights. A term below its floor in storm.adaptive.sla_score.floors fails the whole
+// window, with a score of 0. The latency and the lag are terms only when the SLA sets them (sla_latency and
+// sla_lag); their compliance is the SLA over the value, 1 within it.
+func (c *Controller) updateSlaScore(topology *storm.Topology) {
+	terms := slaTerms{degradation: 1 - math.Min(topology.Degradation, 1)}
+	if c.settings.SlaLatency > 0 {
+		terms.hasLatency = true
+		terms.latency = compliance(c.settings.SlaLatency, topology.Latency)
+	}
+	if c.settings.SlaLag > 0 {
+		terms.hasLag = true
+		terms.lag = compliance(float64(c.settings.SlaLag), float64(topology.Lag))
+	}
+
+	weights, floors := c.settings.SlaWeights, c.settings.SlaFloors
+	topology.SlaScore = 0
+	if terms.degradation < floors.Degradation || terms.hasLatency && terms.latency < floors.Latency ||
+		terms.hasLag && terms.lag < floors.Lag {
+		return
+	}
+	score, weight := weights.Degradation*terms.degradation, weights.Degradation
+	if terms.hasLatency {
+		score += weights.Latency * terms.latency
+		weight += weights.Latency
+	}
+	if terms.hasLag {
+		score += weights.Lag * terms.lag
+		weight += weights.Lag
+	}
+	if weight > 0 {
+		topology.SlaScore = score / weight
+	}
+}
+
+// compliance returns the SLA over the value, 1 when the value is within the SLA.
+func compliance(sla, value float64) float64 {
+	if value <= sla {
+		return 1
+	}
+	return sla / value
+}
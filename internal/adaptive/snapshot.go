@@ -0,0 +1,75 @@
+package adaptive
+
+import (
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"sync"
+)
+
+// snapshotState holds the snapshot published by a Controller
+type snapshotState struct {
+	// snapshot is a copy of the topology at the end of the last cycle, read by the servers of other goroutines
+	// without waiting for the cycle in progress.
+	snapshot     storm.Topology
+	snapshotLock sync.RWMutex
+	// snapshotSummary and snapshotDecisions are the summary and the decisions of the planning at the end of the last
+	// cycle. The decisions share the array of decisions, which only grows past them (appendDecision moves the
+	// decisions kept to a new array), and the completions of the rebalances are written in it under snapshotLock.
+	snapshotSummary   Summary
+	snapshotDecisions []Decision
+	// subscribers receive each snapshot as it is published. A subscriber that is not ready misses the snapshot,
+	// so a slow client never blocks the cycle.
+	subscribers map[chan storm.Topology]struct{}
+}
+
+func (c *Controller) publishSnapshot(topology storm.Topology) {
+	topology = topology.Clone()
+	c.snapshotLock.Lock()
+	defer c.snapshotLock.Unlock()
+	c.snapshot = topology
+	c.snapshotSummary = c.summary
+	c.snapshotDecisions = c.decisions[:len(c.decisions):len(c.decisions)]
+	for subscriber := range c.subscribers {
+		select {
+		case subscriber <- topology:
+		default:
+		}
+	}
+}
+
+// GetSnapshot returns the topology at the end of the last cycle.
+func (c *Controller) GetSnapshot() storm.Topology {
+	c.snapshotLock.RLock()
+	defer c.snapshotLock.RUnlock()
+	return c.snapshot
+}
+
+// GetSnapshotSummary returns the summary of the run at the end of the last cycle.
+func (c *Controller) GetSnapshotSummary() Summary {
+	c.snapshotLock.RLock()
+	defer c.snapshotLock.RUnlock()
+	return c.snapshotSummary
+}
+
+// GetSnapshotDecisions returns a copy of the decisions of the planning at the end of the last cycle.
+func (c *Controller) GetSnapshotDecisions() []Decision {
+	c.snapshotLock.RLock()
+	defer c.snapshotLock.RUnlock()
+	return append([]Decision(nil), c.snapshotDecisions...)
+}
+
+// Subscribe returns a channel that receives the snapshot at the end of each cycle, and the function that
+// closes it.
+func (c *Controller) Subscribe() (<-chan storm.Topology, func()) {
+	subscriber := make(chan storm.Topology, 1)
+	c.snapshotLock.Lock()
+	defer c.snapshotLock.Unlock()
+	c.subscribers[subscriber] = struct{}{}
+	return subscriber, func() {
+		c.snapshotLock.Lock()
+		defer c.snapshotLock.Unlock()
+		if _, ok := c.subscribers[subscriber]; ok {
+			delete(c.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
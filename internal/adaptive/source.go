@@ -0,0 +1,105 @@
+package adaptive
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dwladdimiroc/sps-storm/internal/kafka"
+	"github.com/dwladdimiroc/sps-storm/internal/mqtt"
+	"github.com/dwladdimiroc/sps-storm/internal/nats"
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/spf13/viper"
+)
+
+// InputRateSource counts the tuples that entered the topology since the start. The input rate of a time window
+// is the growth of the count, so a source measures the load from the most reliable signal of the deployment.
+type InputRateSource interface {
+	Total(metrics storm.TopologyMetrics) (int64, error)
+}
+
+// spoutSource counts the tuples emitted by the spouts, from the increments of the counter of each spout in each
+// window. The counters of Storm start again from zero when a worker restarts, so the count keeps growing across
+// the restarts instead of going backwards.
+type spoutSource struct {
+	emitted map[string]int64 // counter of each spout in the previous window
+	total   int64
+}
+
+func (s *spoutSource) Total(metrics storm.TopologyMetrics) (int64, error) {
+	for _, spout := range metrics.Spouts {
+		var emitted int64
+		for _, stats := range spout.SpoutSummary {
+			if stats.Window == ":all-time" {
+				emitted += int64(stats.Emitted)
+			}
+		}
+		if previous, ok := s.emitted[spout.Id]; ok && emitted < previous {
+			log.Printf("monitor: counter reset spout={%s},emitted={%d},previous={%d}\n", spout.Id, emitted, previous)
+		}
+		s.total += counterDelta(emitted, s.emitted[spout.Id])
+		s.emitted[spout.Id] = emitted
+	}
+	return s.total, nil
+}
+
+// counterDelta returns the increment of a cumulative counter since its previous value. A counter lower than
+// its previous value was reset, so the whole counter is the increment.
+func counterDelta(counter, previous int64) int64 {
+	if counter < previous {
+		return counter
+	}
+	return counter - previous
+}
+
+// counterSource counts the messages of a system in front of the spouts, e.g. the offsets of Kafka.
+type counterSource func() (int64, error)
+
+func (c counterSource) Total(storm.TopologyMetrics) (int64, error) {
+	return c()
+}
+
+// httpSource reads the count from an endpoint, e.g. of the producers, whose body is the count as a number.
+type httpSource struct {
+	url    string
+	client *http.Client
+}
+
+func (h httpSource) Total(storm.TopologyMetrics) (int64, error) {
+	res, err := h.client.Get(h.url)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("http counter: %s", res.Status)
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// newInputRateSource returns the InputRateSource of storm.adaptive.input_rate_source.
+func newInputRateSource(name string) InputRateSource {
+	switch name {
+	case "kafka":
+		return counterSource(kafka.Produced)
+	case "mqtt":
+		return counterSource(mqtt.Received)
+	case "nats":
+		return counterSource(nats.Stored)
+	case "http":
+		return httpSource{
+			url:    viper.GetString("http_counter.url"),
+			client: &http.Client{Timeout: time.Duration(viper.GetInt("http_counter.timeout")) * time.Millisecond},
+		}
+	default:
+		return &spoutSource{emitted: make(map[string]int64)}
+	}
+}
@@ -0,0 +1,85 @@
+package adaptive
+
+import (
+	"log"
+
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+)
+
+// State is the state of the topology in the adaptive system, which gates what the planning may do in each
+// window:
+//   - warming, before the first analysis: no plans.
+//   - rebalancing, while the stream processing system reports the topology as rebalancing, a watched rebalance
+//     has not completed, or a bolt rebalanced in the last rebalance_blackout windows: no plans, so a plan is not made on top of the previous one, nor on
+//     its measurements.
+//   - inactive, while the stream processing system reports the topology as inactive or killed: no plans.
+//   - degraded, while the degradation is above degraded_threshold: no scale-down.
+//   - stable, otherwise: any plan.
+//
+// After repeated failures the topology is safe, at its baseline replicas, and it makes no plans until it is
+// resumed.
+type State string
+
+const (
+	Warming     State = "warming"
+	Rebalancing State = "rebalancing"
+	Inactive    State = "inactive"
+	Degraded    State = "degraded"
+	Stable      State = "stable"
+	Safe        State = "safe"
+)
+
+func (s State) allowsPlanning() bool {
+	return s != Warming && s != Rebalancing && s != Inactive && s != Safe
+}
+
+// defersPlanning reports whether a plan blocked in the state is made in the first window that allows it, instead
+// of in the next planning window.
+func (s State) defersPlanning() bool {
+	return s == Rebalancing || s == Inactive
+}
+
+func (s State) allowsScaleDown() bool {
+	return s != Degraded
+}
+
+// updateState sets the state of the topology for the analysis of the window, logging the transitions. When the
+// status of the topology cannot be read, the state follows from the measurements alone.
+func (c *Controller) updateState(topology *storm.Topology) {
+	status, err := c.cluster.GetStatus(topology.Id)
+	if err != nil {
+		log.Printf("[t=%d] state: status,error={%v}\n", c.period*int(c.settings.TimeWindowSize), err)
+		status = storm.StatusActive
+	}
+
+	state := Stable
+	if c.safeMode {
+		state = Safe
+	} else if c.period < c.settings.AnalyzeSamples {
+		state = Warming
+	} else if status == storm.StatusRebalancing || c.rebalancing(*topology) {
+		state = Rebalancing
+	} else if status != storm.StatusActive {
+		state = Inactive
+	} else if c.settings.DegradedThreshold > 0 && topology.Degradation > c.settings.DegradedThreshold {
+		state = Degraded
+	}
+	if string(state) != topology.State {
+		log.Printf("[t=%d] state: from={%s},to={%s}\n", c.period*int(c.settings.TimeWindowSize), topology.State, state)
+		topology.State = string(state)
+	}
+}
+
+// rebalancing reports whether the rebalance of a bolt is pending, or a bolt was rebalanced in the last
+// rebalance_blackout windows, counting the window just measured.
+func (c *Controller) rebalancing(topology storm.Topology) bool {
+	if len(c.pendingBolts) > 0 {
+		return true
+	}
+	for _, bolt := range topology.Bolts {
+		if bolt.RebalancePeriod > 0 && c.period-bolt.RebalancePeriod <= c.settings.RebalanceBlackout {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package adaptive
+
+import (
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"time"
+)
+
+// Summary aggregates the time windows monitored in a run, to compare runs.
+type Summary struct {
+	Windows        int     `csv:"windows" json:"windows"`
+	ReplicaHours   float64 `csv:"replica_hours" json:"replica_hours"`
+	BaselineHours  float64 `csv:"baseline_replica_hours" json:"baseline_replica_hours"`
+	Saving         float64 `csv:"saving" json:"saving"`
+	SlaViolations  int     `csv:"sla_violations" json:"sla_violations"`
+	LatencyAvg     float64 `csv:"latency_avg" json:"latency_avg"`
+	LatencyStd     float64 `csv:"latency_std" json:"latency_std"`
+	LatencyP95     float64 `csv:"latency_p95" json:"latency_p95"`
+	LatencyP99     float64 `csv:"latency_p99" json:"latency_p99"`
+	InputRateAvg   float64 `csv:"input_rate_avg" json:"input_rate_avg"`
+	DegradationAvg float64 `csv:"degradation_avg" json:"degradation_avg"`
+	SlaScoreAvg    float64 `csv:"sla_score_avg" json:"sla_score_avg"`
+	Rebalances     int     `csv:"rebalances" json:"rebalances"`
+	Cost           float64 `csv:"cost" json:"cost"`
+}
+
+// summaryState holds the summary of the run of a Controller
+type summaryState struct {
+	summary Summary
+	// latencySketch summarizes the latency of the windows, so the percentiles of long runs take constant memory
+	latencySketch *util.Sketch
+	// latencyStats accumulates the mean and the variance of the latency of the windows
+	latencyStats util.Welford
+	// decisions are the last storm.adaptive.decisions_limit decisions of the planning, and decisionsForgotten the
+	// decisions before them, so the index of a decision in the run is its index in decisions plus decisionsForgotten
+	decisions          []Decision
+	decisionsForgotten int
+	// lastReplicas are the replicas of each bolt in the previous window, to count the rebalances of the topology
+	lastReplicas map[string]int64
+	// replicaPrice is the price of a replica of a bolt during an hour
+	replicaPrice float64
+}
+
+// Decision is the replicas planned for a bolt in a planning window. When the rebalance is watched, Completed is
+// the time its completion was observed.
+type Decision struct {
+	Period             int       `csv:"period" json:"period"`
+	Profile            string    `csv:"profile" json:"profile"`
+	Bolt               string    `csv:"bolt" json:"bolt"`
+	PredictionReplicas int64     `csv:"prediction_replicas" json:"prediction_replicas"`
+	Replicas           int64     `csv:"replicas" json:"replicas"`
+	Issued             time.Time `csv:"-" json:"issued"`
+	Completed          time.Time `csv:"-" json:"completed"`
+}
+
+func (c *Controller) updateSummary(topology storm.Topology) {
+	c.summary.Windows++
+	rebalanced := false
+	for _, bolt := range topology.Bolts {
+		c.summary.ReplicaHours += float64(bolt.Replicas) * float64(c.settings.TimeWindowSize) / 3600
+		c.summary.BaselineHours += float64(c.baselineReplicas(bolt)) * float64(c.settings.TimeWindowSize) / 3600
+		if replicas, ok := c.lastReplicas[bolt.Name]; ok && replicas != bolt.Replicas {
+			rebalanced = true
+		}
+		c.lastReplicas[bolt.Name] = bolt.Replicas
+	}
+	if c.summary.BaselineHours > 0 {
+		c.summary.Saving = 1 - c.summary.ReplicaHours/c.summary.BaselineHours
+	}
+	if rebalanced {
+		c.summary.Rebalances++
+	}
+	c.summary.Cost += topology.Cost
+	if sla := c.settings.SlaLatency; sla > 0 && topology.Latency > sla {
+		c.summary.SlaViolations++
+	}
+	c.latencyStats.Add(topology.Latency)
+	c.summary.LatencyAvg = c.latencyStats.Mean()
+	c.summary.LatencyStd = c.latencyStats.StdDev()
+	c.latencySketch.Add(topology.Latency)
+	c.summary.LatencyP95 = c.latencySketch.Quantile(0.95)
+	c.summary.LatencyP99 = c.latencySketch.Quantile(0.99)
+	c.summary.InputRateAvg += (float64(topology.InputRateT) - c.summary.InputRateAvg) / float64(c.summary.Windows)
+	c.summary.DegradationAvg += (topology.Degradation - c.summary.DegradationAvg) / float64(c.summary.Windows)
+	c.summary.SlaScoreAvg += (topology.SlaScore - c.summary.SlaScoreAvg) / float64(c.summary.Windows)
+}
+
+// updateCost sets the cost of the replicas of the topology in the time window.
+func (c *Controller) updateCost(topology *storm.Topology) {
+	var replicas int64
+	for _, bolt := range topology.Bolts {
+		replicas += bolt.Replicas
+	}
+	topology.Cost = float64(replicas) * c.replicaPrice * float64(c.settings.TimeWindowSize) / 3600
+}
+
+// appendDecision adds a decision of the planning, forgetting the oldest ones beyond storm.adaptive.decisions_limit.
+// As storm.History, the last decisions are moved to the front once the buffer doubles the limit, but to a new
+// buffer: the snapshot shares the previous one.
+func (c *Controller) appendDecision(decision Decision) {
+	if limit := c.settings.DecisionsLimit; limit > 0 && len(c.decisions) >= 2*limit {
+		decisions := make([]Decision, limit, 2*limit)
+		copy(decisions, c.decisions[len(c.decisions)-limit:])
+		c.decisionsForgotten += len(c.decisions) - limit
+		c.decisions = decisions
+	}
+	c.decisions = append(c.decisions, decision)
+}
+
+// GetSummary returns the summary of the run since New.
+func (c *Controller) GetSummary() Summary {
+	return c.summary
+}
+
+// GetDecisions returns the decisions of the planning since New, in order, up to the last
+// storm.adaptive.decisions_limit of them.
+func (c *Controller) GetDecisions() []Decision {
+	return c.decisions
+}
@@ -1,51 +1,270 @@
 package adaptive
 
 import (
+	"context"
+	"github.com/dwladdimiroc/sps-storm/internal/chaos"
+	"github.com/dwladdimiroc/sps-storm/internal/cost"
 	"github.com/dwladdimiroc/sps-storm/internal/predictive"
 	"github.com/dwladdimiroc/sps-storm/internal/storm"
 	"github.com/dwladdimiroc/sps-storm/internal/util"
 	"github.com/jasonlvhit/gocron"
 	"github.com/spf13/viper"
 	"log"
+	"sync"
 	"time"
 )
 
-var topology *storm.Topology
-var period int
-var schedulerAdaptive *gocron.Scheduler
+// Controller is the adaptive system of one topology: its MAPE loop and the state kept between windows. Each
+// Controller is built by New, so several of them run in the same process.
+type Controller struct {
+	cluster   storm.Cluster
+	topology  *storm.Topology
+	predictor *predictive.Predictor
+	catalog   *predictive.Catalog
 
-func Init(topologyId string) {
-	topology = new(storm.Topology)
-	topology.Init(topologyId)
-	summaryTopology := storm.GetSummaryTopology(topology.Id)
-	topology.CreateTopology(summaryTopology)
-	topology.InitReplicas()
-	log.Printf("Topology created\n")
-	go util.InitServer()
-	predictive.InitPrediction()
-	schedulerAdaptive = gocron.NewScheduler()
+	// settings are the parameters of the config read by each cycle, loaded by New and Reload
+	settings          util.Settings
+	period            int
+	schedulerAdaptive *gocron.Scheduler
+	stopScheduler     chan bool
+
+	// cycle serializes the executions of the adaptive system, since the scheduler runs each job in its own goroutine
+	cycle   sync.Mutex
+	stopped bool
+
+	// inputRateSource counts the tuples that entered the topology, for its input rate
+	inputRateSource InputRateSource
+
+	// outputBolts are the bolts whose executed tuples are the throughput of the topology
+	outputBolts []string
+
+	// deferredPlan is set when the planning of a planning window was deferred by the state of the topology, so the
+	// plan is made in the first window that allows it
+	deferredPlan bool
+
+	baselineState
+	collectState
+	executeState
+	monitorState
+	pipelineState
+	planState
+	safeState
+	snapshotState
+	summaryState
+	watchState
+}
+
+// New returns the Controller of the topology deployed in the cluster, with the topology, its baseline and its
+// predictor loaded.
+func New(cluster storm.Cluster, topologyId string) *Controller {
+	c := newController()
+	c.init(cluster, topologyId)
+	return c
 }
 
-func Start(limit time.Duration) {
-	go func(schedulerAdaptive *gocron.Scheduler) {
-		if err := schedulerAdaptive.Every(uint64(viper.GetInt("storm.adaptive.time_window_size"))).Seconds().Do(adaptiveSystem, topology); err != nil {
-			log.Printf("scheduler: fatal error={%v}", err)
-			return
+func newController() *Controller {
+	return &Controller{
+		collectState:  collectState{collectors: make(map[chan Collection]struct{})},
+		snapshotState: snapshotState{subscribers: make(map[chan storm.Topology]struct{})},
+	}
+}
+
+func (c *Controller) init(cluster storm.Cluster, topologyId string) {
+	c.settings = util.LoadSettings()
+	c.inputRateSource = newInputRateSource(c.settings.InputRateSource)
+	c.cluster = chaos.Wrap(cluster)
+	c.latencySketch = util.NewSketch(0.01)
+	c.lastReplicas = make(map[string]int64)
+	c.spoutLatencies = make(map[string]spoutLatency)
+	if price, err := cost.ReplicaHourPrice(); err != nil {
+		log.Printf("cost: error={%v}\n", err)
+		c.replicaPrice = 0
+	} else {
+		c.replicaPrice = price
+		log.Printf("cost: replica_hour={%.4f %s}\n", price, viper.GetString("cost.currency"))
+	}
+	c.topology = new(storm.Topology)
+	c.topology.Init(topologyId)
+	summaryTopology := c.cluster.GetSummaryTopology(c.topology.Id)
+	c.topology.CreateTopology(c.cluster, summaryTopology)
+	c.initBaseline(summaryTopology)
+	if c.outputBolts = c.settings.OutputBolts; len(c.outputBolts) == 0 {
+		c.outputBolts = c.topology.Sinks()
+	}
+	log.Printf("topology: output bolts={%v}\n", c.outputBolts)
+	c.topology.InitReplicas(c.cluster)
+	c.applied = make(map[string]int64)
+	if !c.settings.DryRun {
+		for _, bolt := range c.topology.Bolts {
+			c.applied[bolt.Name] = bolt.Replicas
 		}
-		<-schedulerAdaptive.Start()
-	}(schedulerAdaptive)
-	time.Sleep(limit)
+	}
+	log.Printf("Topology created\n")
+	if viper.GetBool("features.rest_api") {
+		go util.InitServer()
+	}
+	c.catalog = predictive.NewCatalog()
+	c.predictor = predictive.NewPredictor(predictive.ConfigFromViper(), predictive.ForecasterFromViper(c.catalog))
+	c.schedulerAdaptive = gocron.NewScheduler()
 }
 
-func adaptiveSystem(topology *storm.Topology) {
-	if ok := monitor(topology); ok {
-		if viper.GetBool("storm.deploy.analyze") {
-			analyze(topology)
+// Start runs the adaptive system until the limit is reached or the context is done.
+func (c *Controller) Start(ctx context.Context, limit time.Duration) {
+	if err := c.schedulerAdaptive.Every(uint64(viper.GetInt("storm.adaptive.time_window_size"))).Seconds().Do(c.adaptiveSystem, c.topology); err != nil {
+		log.Printf("scheduler: fatal error={%v}", err)
+		return
+	}
+	if viper.GetBool("storm.adaptive.pipeline") {
+		c.startPipeline(c.topology)
+	}
+	c.stopScheduler = c.schedulerAdaptive.Start()
+
+	select {
+	case <-time.After(limit):
+	case <-ctx.Done():
+		log.Printf("shutdown: signal received\n")
+	}
+}
+
+// Run executes the adaptive system for the given number of time windows, sleeping on the clock of util between
+// them, for clusters whose time is simulated with a logical clock.
+func (c *Controller) Run(windows int) {
+	for i := 0; i < windows; i++ {
+		c.adaptiveSystem(c.topology)
+		util.Sleep(time.Duration(viper.GetInt("storm.adaptive.time_window_size")) * time.Second)
+	}
+}
+
+func (c *Controller) adaptiveSystem(topology *storm.Topology) {
+	c.cycle.Lock()
+	defer c.cycle.Unlock()
+	if c.stopped {
+		return
+	}
+
+	if ok := c.monitor(topology); ok {
+		if c.settings.Analyze && c.analyzeTicks != nil {
+			c.sendAnalyze(c.period)
+		} else if c.settings.Analyze {
+			c.analyze(topology)
+			if c.settings.Planner == "external" {
+				c.externalPlanning(topology, c.period)
+			}
 		}
+		c.publishSnapshot(*topology)
+		c.writeReport(*topology)
 	}
 	topology.ClearStatsTimeWindow()
 }
 
+// Reload loads again the parameters of the config read by each cycle, between two cycles.
+func (c *Controller) Reload() {
+	c.cycle.Lock()
+	defer c.cycle.Unlock()
+	source := c.settings.InputRateSource
+	c.settings = util.LoadSettings()
+	// the counters of the sources start on their first call, so the source is kept until the next start
+	c.settings.InputRateSource = source
+	c.catalog.Load()
+	log.Printf("config: reloaded\n")
+}
+
+// Stop stops the scheduler and waits for the cycle in progress, so its stats are completely written before the
+// process exits. Optionally, the bolts are reverted to the baseline replicas.
+func (c *Controller) Stop() {
+	c.schedulerAdaptive.Clear()
+	if c.stopScheduler != nil {
+		c.stopScheduler <- true
+	}
+	c.stopPipeline()
+
+	c.cycle.Lock()
+	defer c.cycle.Unlock()
+	c.stopped = true
+
+	if viper.GetBool("storm.shutdown.revert_replicas") {
+		for i := range c.topology.Bolts {
+			c.topology.Bolts[i].Replicas = c.baselineReplicas(c.topology.Bolts[i])
+			log.Printf("shutdown: revert bolt={%s},replicas={%d}\n", c.topology.Bolts[i].Name, c.topology.Bolts[i].Replicas)
+		}
+		c.revert(*c.topology)
+	}
+	log.Printf("shutdown: ok\n")
+}
+
+// Default is the Controller behind the functions of the package.
+//
+// Deprecated: create a Controller with New.
+var Default = newController()
+
+// Init replaces Default with the Controller of the topology.
+//
+// Deprecated: use New.
+func Init(cluster storm.Cluster, topologyId string) {
+	Default = New(cluster, topologyId)
+}
+
+// Deprecated: use Controller.Start.
+func Start(ctx context.Context, limit time.Duration) {
+	Default.Start(ctx, limit)
+}
+
+// Deprecated: use Controller.Run.
+func Run(windows int) {
+	Default.Run(windows)
+}
+
+// Deprecated: use Controller.Reload.
+func Reload() {
+	Default.Reload()
+}
+
+// Deprecated: use Controller.Stop.
 func Stop() {
-	schedulerAdaptive.Clear()
+	Default.Stop()
+}
+
+// Deprecated: use Controller.Resume.
+func Resume() error {
+	return Default.Resume()
+}
+
+// Deprecated: use Controller.SubmitPlan.
+func SubmitPlan(replicas map[string]int64) error {
+	return Default.SubmitPlan(replicas)
+}
+
+// Deprecated: use Controller.Subscribe.
+func Subscribe() (<-chan storm.Topology, func()) {
+	return Default.Subscribe()
+}
+
+// Deprecated: use Controller.Collect.
+func Collect() (<-chan Collection, func()) {
+	return Default.Collect()
+}
+
+// Deprecated: use Controller.GetSnapshot.
+func GetSnapshot() storm.Topology {
+	return Default.GetSnapshot()
+}
+
+// Deprecated: use Controller.GetSnapshotSummary.
+func GetSnapshotSummary() Summary {
+	return Default.GetSnapshotSummary()
+}
+
+// Deprecated: use Controller.GetSnapshotDecisions.
+func GetSnapshotDecisions() []Decision {
+	return Default.GetSnapshotDecisions()
+}
+
+// Deprecated: use Controller.GetSummary.
+func GetSummary() Summary {
+	return Default.GetSummary()
+}
+
+// Deprecated: use Controller.GetDecisions.
+func GetDecisions() []Decision {
+	return Default.GetDecisions()
 }
@@ -0,0 +1,96 @@
+package adaptive
+
+import (
+	"log"
+	"time"
+
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+)
+
+// watchState holds the rebalances watched by a Controller
+type watchState struct {
+	// pendingBolts are the bolts whose last rebalance was issued and has not completed yet
+	pendingBolts map[string]bool
+	// watchGeneration identifies the last rebalance watched, so a watcher superseded by a later rebalance, or by the
+	// revert on shutdown, records nothing
+	watchGeneration int
+}
+
+// watchRebalance polls, in the background, the status of the topology after the rebalance of the bolts planned in
+// the window, every storm.adaptive.watch.interval until it is active again. The completion is recorded
+// on the decisions of the rebalance, and the rebalance of the bolts is anchored to the window of the completion,
+// so the blackout counts from it. It is called with the cycle locked.
+func (c *Controller) watchRebalance(topology storm.Topology, window int) {
+	if c.settings.WatchInterval <= 0 {
+		return
+	}
+	bolts := make(map[string]bool)
+	for _, bolt := range topology.Bolts {
+		if bolt.RebalancePeriod == window {
+			bolts[bolt.Name] = true
+		}
+	}
+	if len(bolts) == 0 {
+		return
+	}
+	var watched []int
+	for i := len(c.decisions) - 1; i >= 0 && c.decisions[i].Period == window; i-- {
+		if bolts[c.decisions[i].Bolt] {
+			watched = append(watched, c.decisionsForgotten+i)
+		}
+	}
+
+	c.pendingBolts = bolts
+	c.watchGeneration++
+	generation := c.watchGeneration
+	cluster, id, issued := c.cluster, topology.Id, util.Now()
+	interval := time.Duration(c.settings.WatchInterval) * time.Millisecond
+	deadline := util.Now().Add(time.Duration(c.settings.WatchTimeout) * time.Millisecond)
+	go func() {
+		for {
+			util.Sleep(interval)
+			status, err := cluster.GetStatus(id)
+			if err != nil {
+				log.Printf("rebalance: watch,error={%v}\n", err)
+			} else if status == storm.StatusActive {
+				c.completeRebalance(generation, window, watched, issued, true)
+				return
+			}
+			if util.Now().After(deadline) {
+				c.completeRebalance(generation, window, watched, issued, false)
+				return
+			}
+		}
+	}()
+}
+
+// completeRebalance records the end of the rebalance of the window watched by the generation. A rebalance that
+// timed out is not recorded on its decisions, but it no longer holds the planning.
+func (c *Controller) completeRebalance(generation, window int, watched []int, issued time.Time, completed bool) {
+	c.cycle.Lock()
+	defer c.cycle.Unlock()
+	if generation != c.watchGeneration {
+		return
+	}
+	if completed {
+		now := util.Now()
+		c.snapshotLock.Lock()
+		for _, i := range watched {
+			// the decisions forgotten since the rebalance are not recorded
+			if i -= c.decisionsForgotten; i >= 0 {
+				c.decisions[i].Completed = now
+			}
+		}
+		c.snapshotLock.Unlock()
+		for i := range c.topology.Bolts {
+			if c.pendingBolts[c.topology.Bolts[i].Name] {
+				c.topology.Bolts[i].RebalancePeriod = c.period
+			}
+		}
+		log.Printf("[t=%d] rebalance: completed,window={%d},bolts={%d},duration={%v}\n", c.period, window, len(c.pendingBolts), now.Sub(issued))
+	} else {
+		log.Printf("[t=%d] rebalance: timeout,window={%d},bolts={%d}\n", c.period, window, len(c.pendingBolts))
+	}
+	c.pendingBolts = nil
+}
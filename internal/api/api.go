@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/dwladdimiroc/sps-storm/internal/adaptive"
+	"github.com/dwladdimiroc/sps-storm/internal/control"
+	"github.com/spf13/viper"
+)
+
+// Version is the version of the inspection API, the prefix of its paths.
+const Version = "v1"
+
+// Serve runs the HTTP server of the inspection API on inspection_api.port. It is read-only, and requires the
+// bearer tokens of the control API when they are configured. It serves the snapshot of the controller.
+func Serve(controller *adaptive.Controller) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/"+Version+"/topology", get(func() interface{} { return topologyV1(controller.GetSnapshot()) }))
+	mux.HandleFunc("/api/"+Version+"/summary", get(func() interface{} { return summaryV1(controller.GetSnapshotSummary()) }))
+	mux.HandleFunc("/api/"+Version+"/decisions", get(func() interface{} { return decisionsV1(controller.GetSnapshotDecisions()) }))
+	mux.HandleFunc("/api/"+Version+"/openapi.json", get(func() interface{} { return OpenAPI() }))
+
+	handler, err := control.RequireViewer(mux)
+	if err != nil {
+		log.Printf("inspection api: error={%v}\n", err)
+		return
+	}
+	addr := ":" + viper.GetString("inspection_api.port")
+	log.Printf("inspection api: init,addr={%s}\n", addr)
+	if err := http.ListenAndServe(addr, handler); err != nil {
+		log.Printf("inspection api: error={%v}\n", err)
+	}
+}
+
+// get serves the response of a GET as JSON.
+func get(response func() interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJson(w, http.StatusMethodNotAllowed, ErrorV1{Error: "method not allowed"})
+			return
+		}
+		writeJson(w, http.StatusOK, response())
+	}
+}
+
+func writeJson(w http.ResponseWriter, code int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("inspection api: error={%v}\n", err)
+	}
+}
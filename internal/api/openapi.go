@@ -0,0 +1,142 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Document is the subset of OpenAPI 3.0 used to describe the inspection API.
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type PathItem struct {
+	Get *Operation `json:"get"`
+}
+
+type Operation struct {
+	OperationId string              `json:"operationId"`
+	Summary     string              `json:"summary"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+}
+
+// Schema is the subset of the schema objects of OpenAPI 3.0 used by the responses.
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Nullable    bool               `json:"nullable,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+}
+
+// endpoints are the paths of the inspection API after /api/<Version>, and the type of their response
+var endpoints = []struct {
+	path, operation, summary string
+	response                 interface{}
+}{
+	{"/topology", "getTopology", "Topology at the end of the last time window", TopologyV1{}},
+	{"/summary", "getSummary", "Summary of the time windows of the run", SummaryV1{}},
+	{"/decisions", "getDecisions", "Decisions of the planning since the start, in order", []DecisionV1{}},
+}
+
+// OpenAPI returns the OpenAPI document of the inspection API, derived from the types of its responses.
+func OpenAPI() *Document {
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: "sps-storm inspection API", Version: Version},
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas:         make(map[string]*Schema),
+			SecuritySchemes: map[string]SecurityScheme{"bearer": {Type: "http", Scheme: "bearer"}},
+		},
+		Security: []map[string][]string{{"bearer": {}}},
+	}
+	errorSchema := typeSchema(reflect.TypeOf(ErrorV1{}), doc.Components.Schemas)
+	for _, e := range endpoints {
+		doc.Paths["/api/"+Version+e.path] = PathItem{Get: &Operation{
+			OperationId: e.operation,
+			Summary:     e.summary,
+			Responses: map[string]Response{
+				"200": {Description: e.summary, Content: map[string]MediaType{
+					"application/json": {Schema: typeSchema(reflect.TypeOf(e.response), doc.Components.Schemas)},
+				}},
+				"401": {Description: "Missing or invalid bearer token"},
+				"405": {Description: "Method other than GET", Content: map[string]MediaType{
+					"application/json": {Schema: errorSchema},
+				}},
+			},
+		}}
+	}
+	return doc
+}
+
+// typeSchema returns the schema of the type, adding the schemas of the structs to the components.
+func typeSchema(t reflect.Type, components map[string]*Schema) *Schema {
+	if t == reflect.TypeOf(time.Time{}) {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := typeSchema(t.Elem(), components)
+		schema.Nullable = true
+		return schema
+	case reflect.Slice:
+		return &Schema{Type: "array", Items: typeSchema(t.Elem(), components)}
+	case reflect.Struct:
+		if _, ok := components[t.Name()]; !ok {
+			object := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+			components[t.Name()] = object
+			for i := 0; i < t.NumField(); i++ {
+				field := t.Field(i)
+				name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+				property := typeSchema(field.Type, components)
+				if property.Ref == "" {
+					property.Description = field.Tag.Get("doc")
+				}
+				object.Properties[name] = property
+				object.Required = append(object.Required, name)
+			}
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	default:
+		return &Schema{Type: "string"}
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"time"
+
+	"github.com/dwladdimiroc/sps-storm/internal/adaptive"
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+)
+
+// The responses of version 1 of the inspection API. They are a contract with the dashboards, apart from the
+// structs of the system: a field is only added, never renamed nor removed, within a version. The descriptions of
+// the doc tags go into the OpenAPI document.
+
+// TopologyV1 is the topology at the end of the last time window.
+type TopologyV1 struct {
+	Id                 string   `json:"id" doc:"id of the topology"`
+	Time               int64    `json:"time" doc:"end of the time window since the start (s)"`
+	Profile            string   `json:"profile" doc:"profile of the adaptive parameters"`
+	State              string   `json:"state" doc:"state of the topology: warming, rebalancing, inactive, degraded, stable or safe"`
+	InputRate          int64    `json:"input_rate" doc:"tuples that entered the topology in the window"`
+	InputRateSmooth    float64  `json:"input_rate_smooth" doc:"smoothed input rate"`
+	PredictedInputRate int64    `json:"predicted_input_rate" doc:"input rate predicted for the window"`
+	Latency            float64  `json:"latency" doc:"latency of the topology (ms)"`
+	Lag                int64    `json:"lag" doc:"consumer lag in front of the spouts (tuples)"`
+	Throughput         int64    `json:"throughput" doc:"tuples executed by the output bolts in the window"`
+	Degradation        float64  `json:"degradation" doc:"fraction of the input rate that was not processed"`
+	SlaScore           float64  `json:"sla_score" doc:"compliance of the window with the SLA, from 0 to 1"`
+	Cost               float64  `json:"cost" doc:"cost of the replicas in the window"`
+	Bolts              []BoltV1 `json:"bolts" doc:"bolts of the topology"`
+}
+
+// BoltV1 is a bolt of the topology at the end of the last time window.
+type BoltV1 struct {
+	Name                     string  `json:"name" doc:"name of the bolt"`
+	Replicas                 int64   `json:"replicas" doc:"current replicas"`
+	PredictionReplicas       int64   `json:"prediction_replicas" doc:"replicas predicted for the next planning window"`
+	Input                    int64   `json:"input" doc:"tuples received in the window"`
+	Output                   int64   `json:"output" doc:"tuples executed in the window"`
+	Queue                    int64   `json:"queue" doc:"tuples waiting at the end of the window"`
+	ExecutedTimeAvg          float64 `json:"executed_time_avg" doc:"mean executed time of a tuple (ms)"`
+	ExecutedTimeBenchmarkAvg float64 `json:"executed_time_benchmark_avg" doc:"executed time of a tuple in the benchmark (ms)"`
+	GpuUtilization           float64 `json:"gpu_utilization" doc:"GPU utilization of the replicas (0-100), 0 without GPU metrics"`
+	GpuMemory                float64 `json:"gpu_memory" doc:"GPU memory used by the replicas (MiB), 0 without GPU metrics"`
+}
+
+// SummaryV1 aggregates the time windows of the run.
+type SummaryV1 struct {
+	Windows        int     `json:"windows" doc:"time windows monitored"`
+	ReplicaHours   float64 `json:"replica_hours" doc:"replicas of every bolt times the duration of the windows (h)"`
+	BaselineHours  float64 `json:"baseline_replica_hours" doc:"replica hours of the baseline provisioning of each hour (h)"`
+	Saving         float64 `json:"saving" doc:"fraction of the baseline replica hours saved"`
+	SlaViolations  int     `json:"sla_violations" doc:"windows above the latency of the SLA"`
+	LatencyAvg     float64 `json:"latency_avg" doc:"mean latency (ms)"`
+	LatencyStd     float64 `json:"latency_std" doc:"standard deviation of the latency (ms)"`
+	LatencyP95     float64 `json:"latency_p95" doc:"95th percentile of the latency (ms)"`
+	LatencyP99     float64 `json:"latency_p99" doc:"99th percentile of the latency (ms)"`
+	InputRateAvg   float64 `json:"input_rate_avg" doc:"mean input rate"`
+	DegradationAvg float64 `json:"degradation_avg" doc:"mean degradation"`
+	SlaScoreAvg    float64 `json:"sla_score_avg" doc:"mean SLA score"`
+	Rebalances     int     `json:"rebalances" doc:"windows that changed the replicas of a bolt"`
+	Cost           float64 `json:"cost" doc:"cost of the replicas"`
+}
+
+// DecisionV1 is the replicas planned for a bolt in a planning window.
+type DecisionV1 struct {
+	Period             int        `json:"period" doc:"time window of the planning"`
+	Profile            string     `json:"profile" doc:"profile of the adaptive parameters"`
+	Bolt               string     `json:"bolt" doc:"name of the bolt"`
+	PredictionReplicas int64      `json:"prediction_replicas" doc:"replicas predicted, before the limits"`
+	Replicas           int64      `json:"replicas" doc:"replicas planned"`
+	Issued             time.Time  `json:"issued" doc:"time of the plan"`
+	Completed          *time.Time `json:"completed" doc:"time the rebalance was observed complete, null when it was not watched"`
+}
+
+// ErrorV1 is the body of a failed request.
+type ErrorV1 struct {
+	Error string `json:"error" doc:"reason of the failure"`
+}
+
+func topologyV1(topology storm.Topology) TopologyV1 {
+	t := TopologyV1{
+		Id:                 topology.Id,
+		Time:               topology.Time,
+		Profile:            topology.Profile,
+		State:              topology.State,
+		InputRate:          topology.InputRateT,
+		InputRateSmooth:    topology.InputRateSmooth,
+		PredictedInputRate: topology.PredictedInputRateT,
+		Latency:            topology.Latency,
+		Lag:                topology.Lag,
+		Throughput:         topology.Throughput,
+		Degradation:        topology.Degradation,
+		SlaScore:           topology.SlaScore,
+		Cost:               topology.Cost,
+		Bolts:              []BoltV1{},
+	}
+	for _, bolt := range topology.Bolts {
+		t.Bolts = append(t.Bolts, BoltV1{
+			Name:                     bolt.Name,
+			Replicas:                 bolt.Replicas,
+			PredictionReplicas:       bolt.PredictionReplicas,
+			Input:                    bolt.Input,
+			Output:                   bolt.Output,
+			Queue:                    bolt.Queue,
+			ExecutedTimeAvg:          bolt.ExecutedTimeAvg,
+			ExecutedTimeBenchmarkAvg: bolt.ExecutedTimeBenchmarkAvg,
+			GpuUtilization:           bolt.GpuUtilization,
+			GpuMemory:                bolt.GpuMemory,
+		})
+	}
+	return t
+}
+
+func summaryV1(summary adaptive.Summary) SummaryV1 {
+	return SummaryV1{
+		Windows:        summary.Windows,
+		ReplicaHours:   summary.ReplicaHours,
+		BaselineHours:  summary.BaselineHours,
+		Saving:         summary.Saving,
+		SlaViolations:  summary.SlaViolations,
+		LatencyAvg:     summary.LatencyAvg,
+		LatencyStd:     summary.LatencyStd,
+		LatencyP95:     summary.LatencyP95,
+		LatencyP99:     summary.LatencyP99,
+		InputRateAvg:   summary.InputRateAvg,
+		DegradationAvg: summary.DegradationAvg,
+		SlaScoreAvg:    summary.SlaScoreAvg,
+		Rebalances:     summary.Rebalances,
+		Cost:           summary.Cost,
+	}
+}
+
+func decisionsV1(decisions []adaptive.Decision) []DecisionV1 {
+	list := make([]DecisionV1, 0, len(decisions))
+	for _, d := range decisions {
+		decision := DecisionV1{
+			Period:             d.Period,
+			Profile:            d.Profile,
+			Bolt:               d.Bolt,
+			PredictionReplicas: d.PredictionReplicas,
+			Replicas:           d.Replicas,
+			Issued:             d.Issued,
+		}
+		if !d.Completed.IsZero() {
+			completed := d.Completed
+			decision.Completed = &completed
+		}
+		list = append(list, decision)
+	}
+	return list
+}
@@ -9,11 +9,12 @@ import (
 
 const DirCmd = "scripts"
 
-func Deploy() string {
+// Deploy runs the app script and returns the id of the topology deployed in the cluster.
+func Deploy(cluster storm.Cluster) string {
 	appCmdStormApp := "sh"
 	argsCmdStormApp := []string{viper.GetString("storm.deploy.script"), viper.GetString("storm.deploy.dataset"), strconv.Itoa(viper.GetInt("storm.adaptive.limit_replicas"))}
 	dirCmdStormApp := DirCmd
 	util.Execute(appCmdStormApp, argsCmdStormApp, dirCmdStormApp)
-	topologyId := storm.GetTopologyId()
+	topologyId := cluster.GetTopologyId()
 	return topologyId
 }
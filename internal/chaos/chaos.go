@@ -0,0 +1,154 @@
+package chaos
+
+import (
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/spf13/viper"
+	"log"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// Cluster wraps a cluster and injects measurement problems in its responses: relative gaussian noise on the
+// metrics, dropped responses of the metrics API and delayed responses. The counters of Storm are cumulative, so
+// the noise is applied on the increment of each counter, and the noisy counter never goes backwards, except
+// when a restart of the workers is injected and every counter starts again from zero.
+type Cluster struct {
+	storm.Cluster
+	rng      *rand.Rand
+	ackedRng *rand.Rand // noise of the acked counters, apart so that it does not shift the noise of the others
+	raw      map[string]int64
+	noisy    map[string]int64
+	noise    float64
+	dropout  float64
+	restart  float64
+	delay    time.Duration
+	metrics  int
+	dropouts int
+	restarts int
+}
+
+// Wrap returns the cluster wrapped by the chaos layer when chaos.enabled is set, else the cluster itself.
+func Wrap(c storm.Cluster) storm.Cluster {
+	if !viper.GetBool("chaos.enabled") {
+		return c
+	}
+	log.Printf("chaos: noise={%.2f},dropout={%.2f},restart={%.2f},delay={%dms}\n", viper.GetFloat64("chaos.noise"), viper.GetFloat64("chaos.dropout"), viper.GetFloat64("chaos.restart"), viper.GetInt("chaos.delay"))
+	return &Cluster{
+		Cluster:  c,
+		rng:      rand.New(rand.NewSource(viper.GetInt64("chaos.seed"))),
+		ackedRng: rand.New(rand.NewSource(viper.GetInt64("chaos.seed") + 1)),
+		raw:      make(map[string]int64),
+		noisy:    make(map[string]int64),
+		noise:    viper.GetFloat64("chaos.noise"),
+		dropout:  viper.GetFloat64("chaos.dropout"),
+		restart:  viper.GetFloat64("chaos.restart"),
+		delay:    time.Duration(viper.GetInt("chaos.delay")) * time.Millisecond,
+	}
+}
+
+func (c *Cluster) GetMetrics(topology storm.Topology) (bool, storm.TopologyMetrics) {
+	c.wait()
+	ok, metrics := c.Cluster.GetMetrics(topology)
+	c.metrics++
+	if ok && c.rng.Float64() < c.dropout {
+		c.dropouts++
+		log.Printf("chaos: drop metrics,dropouts={%d/%d}\n", c.dropouts, c.metrics)
+		return false, storm.TopologyMetrics{}
+	}
+	if !ok {
+		return ok, metrics
+	}
+	if c.restart > 0 && c.rng.Float64() < c.restart {
+		c.restarts++
+		log.Printf("chaos: restart workers,restarts={%d/%d}\n", c.restarts, c.metrics)
+		for key := range c.noisy {
+			c.noisy[key] = 0
+		}
+	}
+
+	for i := range metrics.Spouts {
+		spout := &metrics.Spouts[i]
+		for j := range spout.SpoutSummary {
+			key := "spout/" + spout.Id + "/" + spout.SpoutSummary[j].Window
+			spout.SpoutSummary[j].Emitted = int(c.counter(key, int64(spout.SpoutSummary[j].Emitted)))
+			spout.SpoutSummary[j].Acked = int(c.counterFrom(c.ackedRng, key+"/acked", int64(spout.SpoutSummary[j].Acked)))
+		}
+		for j := range spout.OutputStats {
+			key := "spout/" + spout.Id + "/" + spout.OutputStats[j].Stream
+			spout.OutputStats[j].Emitted = int(c.counter(key, int64(spout.OutputStats[j].Emitted)))
+		}
+	}
+	for i := range metrics.Bolts {
+		bolt := &metrics.Bolts[i]
+		for j := range bolt.BoltStats {
+			key := "bolt/" + bolt.Id + "/" + bolt.BoltStats[j].Window
+			bolt.BoltStats[j].Executed = c.counter(key, bolt.BoltStats[j].Executed)
+			if executeLatency, err := strconv.ParseFloat(bolt.BoltStats[j].ExecuteLatency, 64); err == nil {
+				bolt.BoltStats[j].ExecuteLatency = strconv.FormatFloat(c.gauge(executeLatency), 'f', 3, 64)
+			}
+		}
+		for j := range bolt.OutputStats {
+			key := "bolt/" + bolt.Id + "/" + bolt.OutputStats[j].Stream
+			bolt.OutputStats[j].Emitted = c.counter(key, bolt.OutputStats[j].Emitted)
+		}
+	}
+	return ok, metrics
+}
+
+// SetReplicasBatch forwards the update to the wrapped cluster, in a single update when it is a BatchCluster.
+func (c *Cluster) SetReplicasBatch(replicas map[string]int64) error {
+	if batch, ok := c.Cluster.(storm.BatchCluster); ok {
+		return batch.SetReplicasBatch(replicas)
+	}
+	var err error
+	for bolt, n := range replicas {
+		if errSet := c.Cluster.SetReplicas(bolt, n); errSet != nil {
+			err = errSet
+		}
+	}
+	return err
+}
+
+func (c *Cluster) GetLatency() float64 {
+	c.wait()
+	return c.gauge(c.Cluster.GetLatency())
+}
+
+func (c *Cluster) GetComponentBolt(topologyId, boltName string) storm.BoltMetrics {
+	c.wait()
+	return c.Cluster.GetComponentBolt(topologyId, boltName)
+}
+
+// wait delays the response a random time up to chaos.delay, on the clock of util.
+func (c *Cluster) wait() {
+	if c.delay > 0 {
+		util.Sleep(time.Duration(c.rng.Int63n(int64(c.delay))))
+	}
+}
+
+func (c *Cluster) gauge(value float64) float64 {
+	return c.gaugeFrom(c.rng, value)
+}
+
+func (c *Cluster) gaugeFrom(rng *rand.Rand, value float64) float64 {
+	if value = value * (1 + rng.NormFloat64()*c.noise); value < 0 {
+		return 0
+	}
+	return value
+}
+
+func (c *Cluster) counter(key string, value int64) int64 {
+	return c.counterFrom(c.rng, key, value)
+}
+
+// counterFrom adds the noise to the increment of the counter, drawn from rng.
+func (c *Cluster) counterFrom(rng *rand.Rand, key string, value int64) int64 {
+	increment := value - c.raw[key]
+	c.raw[key] = value
+	if increment > 0 {
+		c.noisy[key] += int64(c.gaugeFrom(rng, float64(increment)))
+	}
+	return c.noisy[key]
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"encoding/json"
+	"github.com/dwladdimiroc/sps-storm/internal/api"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var apiCmd = &cobra.Command{
+	Use:   "api",
+	Short: "Describe the inspection API",
+}
+
+var apiOpenapiCmd = &cobra.Command{
+	Use:   "openapi",
+	Short: "Print the OpenAPI document of the inspection API",
+	Args:  cobra.NoArgs,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(api.OpenAPI())
+	},
+}
+
+func init() {
+	apiCmd.AddCommand(apiOpenapiCmd)
+	rootCmd.AddCommand(apiCmd)
+}
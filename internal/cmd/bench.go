@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/experiment"
+	"github.com/montanaflynn/stats"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"io"
+	"log"
+	"os"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Run a matrix of predictive models, presets and workloads through the simulator and summarize it as CSV",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		models, _ := cmd.Flags().GetStringSlice("models")
+		presets, _ := cmd.Flags().GetStringSlice("presets")
+		workloads, _ := cmd.Flags().GetStringSlice("workloads")
+		seeds, _ := cmd.Flags().GetInt("seeds")
+		output, _ := cmd.Flags().GetString("output")
+		if verbose, _ := cmd.Flags().GetBool("verbose"); !verbose {
+			log.SetOutput(io.Discard)
+			defer log.SetOutput(os.Stderr)
+		}
+
+		w := os.Stdout
+		if output != "" {
+			if f, err := os.Create(output); err != nil {
+				return fmt.Errorf("bench: %v", err)
+			} else {
+				defer f.Close()
+				w = f
+			}
+		}
+
+		// Every run of the matrix is in memory, the CSV files and the REST server are not needed
+		viper.Set("features.exporters", false)
+		viper.Set("features.rest_api", false)
+
+		fmt.Fprintln(w, "model,preset,workload,runs,sla_violations_mean,sla_violations_std,replica_hours_mean,replica_hours_std,latency_avg_mean,latency_avg_std")
+		for _, model := range models {
+			for _, preset := range presets {
+				for _, kind := range workloads {
+					viper.Set("storm.adaptive.predictive_model", model)
+					if err := experiment.ApplyPreset(preset); err != nil {
+						return fmt.Errorf("bench: %v", err)
+					}
+					viper.Set("workload.kind", kind)
+
+					var violations, replicaHours, latency []float64
+					for seed := 1; seed <= seeds; seed++ {
+						viper.Set("workload.seed", seed)
+						trace, err := experiment.GenerateWorkload()
+						if err != nil {
+							return fmt.Errorf("bench: %v", err)
+						}
+						id := fmt.Sprintf("bench-%s-%s-%s-%d", model, preset, kind, seed)
+						summary, err := experiment.Simulate(id, trace)
+						if err != nil {
+							return fmt.Errorf("bench: %v", err)
+						}
+						violations = append(violations, float64(summary.SlaViolations))
+						replicaHours = append(replicaHours, summary.ReplicaHours)
+						latency = append(latency, summary.LatencyAvg)
+					}
+					fmt.Fprintf(w, "%s,%s,%s,%d,%s,%s,%s\n", model, preset, kind, seeds,
+						meanStd(violations), meanStd(replicaHours), meanStd(latency))
+				}
+			}
+		}
+		return nil
+	},
+}
+
+func meanStd(data []float64) string {
+	mean, _ := stats.Mean(data)
+	std, _ := stats.StandardDeviation(data)
+	return fmt.Sprintf("%.3f,%.3f", mean, std)
+}
+
+func init() {
+	benchCmd.Flags().StringSlice("models", []string{"basic"}, "predictive models of the matrix")
+	benchCmd.Flags().StringSlice("presets", []string{"conservative", "balanced", "aggressive"}, "presets of the matrix")
+	benchCmd.Flags().StringSlice("workloads", []string{"sinusoidal", "flash_crowd"}, "workload kinds of the matrix")
+	benchCmd.Flags().Int("seeds", 5, "number of seeds run for each cell of the matrix")
+	benchCmd.Flags().StringP("output", "o", "", "file of the CSV (default is stdout)")
+	benchCmd.Flags().Bool("verbose", false, "keep the log of the adaptive system")
+	rootCmd.AddCommand(benchCmd)
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/flink"
+	"github.com/dwladdimiroc/sps-storm/internal/heron"
+	"github.com/dwladdimiroc/sps-storm/internal/kubernetes"
+	"github.com/dwladdimiroc/sps-storm/internal/prometheus"
+	"github.com/dwladdimiroc/sps-storm/internal/pulsar"
+	"github.com/dwladdimiroc/sps-storm/internal/spark"
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/spf13/viper"
+)
+
+// newCluster creates the cluster of the backend, wrapped by the metrics source and by the worker scaling of
+// Kubernetes when enabled.
+func newCluster() (storm.Cluster, error) {
+	var cluster storm.Cluster
+	switch backend := viper.GetString("backend"); backend {
+	case "storm":
+		cluster = storm.Storm{}
+	case "flink":
+		cluster = flink.New()
+	case "heron":
+		cluster = heron.New()
+	case "pulsar":
+		cluster = pulsar.New()
+	case "spark":
+		cluster = spark.New()
+	default:
+		return nil, fmt.Errorf("cluster: unknown backend %q", backend)
+	}
+
+	switch source := viper.GetString("metrics_source"); source {
+	case "cluster":
+	case "prometheus":
+		if metrics, err := prometheus.New(cluster); err != nil {
+			return nil, err
+		} else {
+			cluster = metrics
+		}
+	default:
+		return nil, fmt.Errorf("cluster: unknown metrics source %q", source)
+	}
+
+	if viper.GetBool("kubernetes.workers.enabled") {
+		if workers, err := kubernetes.NewWorkers(cluster); err != nil {
+			return nil, err
+		} else {
+			cluster = workers
+		}
+	}
+	return cluster, nil
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare <topology-id-a> <topology-id-b>",
+	Short: "Compare the statistics saved by two runs",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := readRun(args[0])
+		if err != nil {
+			return fmt.Errorf("compare: %v", err)
+		}
+		b, err := readRun(args[1])
+		if err != nil {
+			return fmt.Errorf("compare: %v", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "\t%s\t%s\tdiff\n", args[0], args[1])
+		for _, row := range []struct {
+			name   string
+			format string
+			a, b   float64
+		}{
+			{"windows", "%.0f", float64(len(a.samples)), float64(len(b.samples))},
+			{"sla score", "%.3f", a.slaScore, b.slaScore},
+			{"sla violations", "%.0f", float64(a.slaViolations), float64(b.slaViolations)},
+			{"replica hours", "%.3f", a.replicaHours, b.replicaHours},
+			{"latency avg", "%.2f", a.latencyAvg, b.latencyAvg},
+			{"cost", "%.3f", a.cost, b.cost},
+		} {
+			format := "%s\t" + row.format + "\t" + row.format + "\t%+" + row.format[1:] + "\n"
+			fmt.Fprintf(w, format, row.name, row.a, row.b, row.b-row.a)
+		}
+		fmt.Fprintln(w, "\t\t\t")
+		fmt.Fprintf(w, "model\t%s\t%s\t\n", args[0], args[1])
+		for _, model := range modelNames(a.models, b.models) {
+			fmt.Fprintf(w, "%s\t%.1f%%\t%.1f%%\t\n", model, a.modelShare(model), b.modelShare(model))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if output, _ := cmd.Flags().GetString("output"); output != "" {
+			if err := writeComparison(output, a, b); err != nil {
+				return fmt.Errorf("compare: %v", err)
+			}
+		}
+		return nil
+	},
+}
+
+// run is the statistics saved by a run, aggregated as in the summary of the adaptive system. The SLA score adds
+// up the score of the windows, and the violations are the windows above storm.adaptive.sla_latency.
+type run struct {
+	samples       []storm.Topology
+	replicas      map[int64]int64
+	slaScore      float64
+	slaViolations int
+	replicaHours  float64
+	latencyAvg    float64
+	cost          float64
+	models        map[string]int
+}
+
+func readRun(topologyId string) (run, error) {
+	r := run{replicas: make(map[int64]int64), models: make(map[string]int)}
+	if err := util.ReadCsv(topologyId, "Topology", &r.samples); err != nil {
+		return r, err
+	}
+	bolts, err := readBolts(topologyId)
+	if err != nil {
+		return r, err
+	}
+	for _, samples := range bolts {
+		for _, sample := range samples {
+			r.replicas[sample.Time] += sample.Replicas
+		}
+	}
+
+	sla := viper.GetFloat64("storm.adaptive.sla_latency")
+	for i, sample := range r.samples {
+		r.slaScore += sample.SlaScore
+		if sla > 0 && sample.Latency > sla {
+			r.slaViolations++
+		}
+		r.latencyAvg += (sample.Latency - r.latencyAvg) / float64(i+1)
+		r.cost += sample.Cost
+		r.models[sample.PredictModel]++
+	}
+	// The window of the run is the time between its samples
+	if len(r.samples) > 1 {
+		window := float64(r.samples[1].Time - r.samples[0].Time)
+		for _, sample := range r.samples {
+			r.replicaHours += float64(r.replicas[sample.Time]) * window / 3600
+		}
+	}
+	return r, nil
+}
+
+// modelShare returns the percentage of the windows predicted by the model.
+func (r run) modelShare(model string) float64 {
+	if len(r.samples) == 0 {
+		return 0
+	}
+	return 100 * float64(r.models[model]) / float64(len(r.samples))
+}
+
+func modelNames(a, b map[string]int) []string {
+	var names []string
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeComparison writes the windows of both runs side by side as CSV, joined by time, to plot them. The cells of
+// a run without that window are empty.
+func writeComparison(filename string, a, b run) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	samplesA, samplesB := make(map[int64]storm.Topology), make(map[int64]storm.Topology)
+	var times []int64
+	for _, sample := range a.samples {
+		samplesA[sample.Time] = sample
+		times = append(times, sample.Time)
+	}
+	for _, sample := range b.samples {
+		samplesB[sample.Time] = sample
+		if _, ok := samplesA[sample.Time]; !ok {
+			times = append(times, sample.Time)
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	fmt.Fprintln(f, "time,latency_a,latency_b,sla_score_a,sla_score_b,replicas_a,replicas_b")
+	cells := func(r run, samples map[int64]storm.Topology, time int64) (string, string, string) {
+		if sample, ok := samples[time]; ok {
+			return fmt.Sprintf("%.2f", sample.Latency), fmt.Sprintf("%.3f", sample.SlaScore), fmt.Sprint(r.replicas[time])
+		}
+		return "", "", ""
+	}
+	for _, time := range times {
+		latencyA, slaScoreA, replicasA := cells(a, samplesA, time)
+		latencyB, slaScoreB, replicasB := cells(b, samplesB, time)
+		if _, err := fmt.Fprintf(f, "%d,%s,%s,%s,%s,%s,%s\n", time, latencyA, latencyB, slaScoreA, slaScoreB, replicasA, replicasB); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	compareCmd.Flags().StringP("output", "o", "", "file of the windows of both runs as CSV, to plot them")
+	rootCmd.AddCommand(compareCmd)
+}
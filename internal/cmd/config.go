@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the configuration file",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the configuration file",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if errs := util.ValidateConfig(); len(errs) > 0 {
+			for _, err := range errs {
+				fmt.Printf("config: %v\n", err)
+			}
+			return fmt.Errorf("config %s: %d errors", viper.ConfigFileUsed(), len(errs))
+		}
+		fmt.Printf("config %s: ok\n", viper.ConfigFileUsed())
+		return nil
+	},
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the config file",
+	Args:  cobra.NoArgs,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(util.ConfigSchema())
+	},
+}
+
+var configInitForce bool
+
+var configInitCmd = &cobra.Command{
+	Use:   "init [file]",
+	Short: "Write a commented config file with the defaults",
+	Long:  "Write a commented config file with the defaults of the code (to stdout when the file is \"-\").",
+	Args:  cobra.MaximumNArgs(1),
+	// The generated file comes from the defaults of the code, so the current config is not loaded
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filename := "configs/config.yaml"
+		if len(args) > 0 {
+			filename = args[0]
+		}
+		if filename == "-" {
+			return util.WriteDefaultConfig(os.Stdout)
+		}
+
+		if _, err := os.Stat(filename); err == nil && !configInitForce {
+			return fmt.Errorf("config init: %s already exists (use --force to overwrite it)", filename)
+		}
+		f, err := os.Create(filename)
+		if err != nil {
+			return fmt.Errorf("config init: %v", err)
+		}
+		defer f.Close()
+		if err := util.WriteDefaultConfig(f); err != nil {
+			return fmt.Errorf("config init: %v", err)
+		}
+		fmt.Printf("config init: %s written\n", filename)
+		return nil
+	},
+}
+
+func init() {
+	configInitCmd.Flags().BoolVar(&configInitForce, "force", false, "overwrite the file if it exists")
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configSchemaCmd)
+	rootCmd.AddCommand(configCmd)
+}
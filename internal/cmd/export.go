@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+	"strconv"
+)
+
+var exportOutput string
+
+var exportCmd = &cobra.Command{
+	Use:   "export <topology-id>",
+	Short: "Export the statistics saved by a run as JSON",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		topologyId := args[0]
+
+		filenames, err := util.ListCsv(topologyId)
+		if err != nil {
+			return fmt.Errorf("export: %v", err)
+		}
+		export := make(map[string][]map[string]interface{})
+		for _, filename := range filenames {
+			if export[filename], err = readRecords(viper.GetString("storm.csv") + "/" + topologyId + "/" + filename + ".csv"); err != nil {
+				return fmt.Errorf("export %s: %v", filename, err)
+			}
+		}
+
+		var w io.Writer = os.Stdout
+		if exportOutput != "" {
+			f, err := os.Create(exportOutput)
+			if err != nil {
+				return fmt.Errorf("export: %v", err)
+			}
+			defer f.Close()
+			w = f
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(export)
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "output file (default is stdout)")
+	rootCmd.AddCommand(exportCmd)
+}
+
+// readRecords reads a csv file as a list of records keyed by the header, keeping numeric values as numbers.
+func readRecords(filename string) ([]map[string]interface{}, error) {
+	var records []map[string]interface{}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return records, err
+	}
+	defer f.Close()
+
+	lines, err := csv.NewReader(f).ReadAll()
+	if err != nil || len(lines) == 0 {
+		return records, err
+	}
+	header := lines[0]
+	for _, line := range lines[1:] {
+		record := make(map[string]interface{})
+		for i, value := range line {
+			if number, err := strconv.ParseFloat(value, 64); err == nil {
+				record[header[i]] = number
+			} else {
+				record[header[i]] = value
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/experiment"
+	"github.com/spf13/cobra"
+	"io"
+	"log"
+	"os"
+)
+
+var goldenCmd = &cobra.Command{
+	Use:   "golden",
+	Short: "Compare the decisions of the planning on a committed trace with the golden output",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		scenarioFile, _ := cmd.Flags().GetString("scenario")
+		golden, _ := cmd.Flags().GetString("golden")
+		update, _ := cmd.Flags().GetBool("update")
+		if verbose, _ := cmd.Flags().GetBool("verbose"); !verbose {
+			log.SetOutput(io.Discard)
+			defer log.SetOutput(os.Stderr)
+		}
+
+		scenario, err := experiment.ReadScenario(scenarioFile)
+		if err != nil {
+			return err
+		}
+		diffs, err := experiment.Golden(scenario, golden, update)
+		if err != nil {
+			return err
+		}
+		if update {
+			fmt.Printf("golden: updated %s\n", golden)
+			return nil
+		}
+		for _, diff := range diffs {
+			fmt.Println(diff)
+		}
+		if len(diffs) > 0 {
+			return fmt.Errorf("golden: %d decisions differ from %s", len(diffs), golden)
+		}
+		fmt.Println("golden: ok")
+		return nil
+	},
+}
+
+func init() {
+	goldenCmd.Flags().String("scenario", "scenarios/golden.yaml", "scenario of the golden run")
+	goldenCmd.Flags().String("golden", "scenarios/golden/decisions.csv", "golden decisions of the planning")
+	goldenCmd.Flags().Bool("update", false, "write the golden decisions instead of comparing them")
+	goldenCmd.Flags().Bool("verbose", false, "keep the log of the adaptive system")
+	rootCmd.AddCommand(goldenCmd)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/montanaflynn/stats"
+	"github.com/spf13/cobra"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <topology-id>",
+	Short: "Summarize the statistics saved by a run",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		topologyId := args[0]
+
+		var samples []storm.Topology
+		if err := util.ReadCsv(topologyId, "Topology", &samples); err != nil {
+			return fmt.Errorf("inspect: %v", err)
+		}
+		var inputRate, latency []float64
+		for _, sample := range samples {
+			inputRate = append(inputRate, float64(sample.InputRateT))
+			latency = append(latency, sample.Latency)
+		}
+		inputAvg, _ := stats.Mean(inputRate)
+		inputMax, _ := stats.Max(inputRate)
+		latencyAvg, _ := stats.Mean(latency)
+		fmt.Printf("topology: %s\nsamples: %d\ninput rate: avg=%.2f max=%.0f\nlatency: avg=%.2f\n\n", topologyId, len(samples), inputAvg, inputMax, latencyAvg)
+
+		bolts, err := readBolts(topologyId)
+		if err != nil {
+			return fmt.Errorf("inspect: %v", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "bolt\treplicas avg\treplicas max\tqueue avg\texecuted time avg")
+		for _, name := range sortedNames(bolts) {
+			samples := bolts[name]
+			var replicas, queue, executedTime []float64
+			for _, sample := range samples {
+				replicas = append(replicas, float64(sample.Replicas))
+				queue = append(queue, float64(sample.Queue))
+				executedTime = append(executedTime, sample.ExecutedTimeAvg)
+			}
+			replicasAvg, _ := stats.Mean(replicas)
+			replicasMax, _ := stats.Max(replicas)
+			queueAvg, _ := stats.Mean(queue)
+			executedTimeAvg, _ := stats.Mean(executedTime)
+			fmt.Fprintf(w, "%s\t%.2f\t%.0f\t%.2f\t%.4f\n", name, replicasAvg, replicasMax, queueAvg, executedTimeAvg)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+}
+
+// readBolts reads the statistics of every bolt saved by a run, keyed by bolt name.
+func readBolts(topologyId string) (map[string][]storm.Bolt, error) {
+	bolts := make(map[string][]storm.Bolt)
+	filenames, err := util.ListCsv(topologyId)
+	if err != nil {
+		return bolts, err
+	}
+	for _, filename := range filenames {
+		if filename == "Topology" {
+			continue
+		}
+		var samples []storm.Bolt
+		if err := util.ReadCsv(topologyId, filename, &samples); err != nil {
+			return bolts, err
+		}
+		bolts[filename] = samples
+	}
+	return bolts, nil
+}
+
+func sortedNames(bolts map[string][]storm.Bolt) []string {
+	var names []string
+	for name := range bolts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/adaptive"
+	"github.com/dwladdimiroc/sps-storm/internal/app"
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"log"
+	"os"
+	"os/exec"
+)
+
+var integrationCmd = &cobra.Command{
+	Use:   "integration",
+	Short: "Run the self-adaptive system end-to-end against a dockerized Storm cluster",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		compose, _ := cmd.Flags().GetString("compose")
+		windows, _ := cmd.Flags().GetInt("windows")
+		keep, _ := cmd.Flags().GetBool("keep")
+
+		if err := docker("compose", "-f", compose, "up", "-d", "--wait"); err != nil {
+			return fmt.Errorf("integration: cluster up: %v", err)
+		}
+		if !keep {
+			defer func() {
+				if err := docker("compose", "-f", compose, "down"); err != nil {
+					log.Printf("integration: cluster down: %v\n", err)
+				}
+			}()
+		}
+
+		// The sample topology is submitted through the Nimbus container, and the predictor is not part of the cluster
+		viper.Set("storm.deploy.script", "integrationApp.sh")
+		viper.Set("features.prediction", false)
+		topologyId := app.Deploy(storm.Storm{})
+		log.Printf("integration: topology={%s}\n", topologyId)
+
+		controller := adaptive.New(storm.Storm{}, topologyId)
+		controller.Run(windows)
+		controller.Stop()
+
+		summary := controller.GetSummary()
+		fmt.Printf("integration: windows=%d/%d,rebalances=%d,latency_avg=%.2f\n", summary.Windows, windows, summary.Rebalances, summary.LatencyAvg)
+		if summary.Windows != windows {
+			return fmt.Errorf("integration: %d of %d windows were closed", summary.Windows, windows)
+		}
+		if viper.GetBool("storm.deploy.analyze") && summary.Rebalances == 0 {
+			return fmt.Errorf("integration: the topology was never rebalanced")
+		}
+		fmt.Println("integration: ok")
+		return nil
+	},
+}
+
+func docker(args ...string) error {
+	log.Printf("Executing docker %v\n", args)
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func init() {
+	integrationCmd.Flags().String("compose", "deployments/integration/docker-compose.yml", "compose file of the Storm cluster")
+	integrationCmd.Flags().Int("windows", 120, "number of time windows of the run")
+	integrationCmd.Flags().Bool("keep", false, "keep the cluster running at the end")
+	integrationCmd.Flags().String("dataset", "", "dataset used by the app script")
+	integrationCmd.Flags().Bool("analyze", true, "adapt the Storm application")
+	integrationCmd.Flags().Int("window", 0, "size of the monitor time window (seconds)")
+	rootCmd.AddCommand(integrationCmd)
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/predictive"
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"math"
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <topology-id>",
+	Short: "Replay the input rate recorded by a run through a predictive model",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var samples []storm.Topology
+		if err := util.ReadCsv(args[0], "Topology", &samples); err != nil {
+			return fmt.Errorf("replay: %v", err)
+		}
+
+		inputRate := make([]int64, len(samples))
+		for i := range samples {
+			inputRate[i] = samples[i].InputRateT
+		}
+		predicted := predictive.Replay(inputRate)
+
+		var absError float64
+		fmt.Println("time,input_rate,predicted_input_rate")
+		for i := range samples {
+			fmt.Printf("%d,%d,%d\n", samples[i].Time, inputRate[i], predicted[i])
+			absError += math.Abs(float64(inputRate[i] - predicted[i]))
+		}
+		if len(samples) > 0 {
+			fmt.Printf("# model=%s mae=%.2f\n", viper.GetString("storm.adaptive.predictive_model"), absError/float64(len(samples)))
+		}
+		return nil
+	},
+}
+
+func init() {
+	replayCmd.Flags().String("model", "", "model used by input prediction")
+	replayCmd.Flags().Int("samples", 0, "number of samples used by the predictive model")
+	replayCmd.Flags().Int("predictions", 0, "number of predictions made by the predictive model")
+	rootCmd.AddCommand(replayCmd)
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+)
+
+var configFile string
+
+var rootCmd = &cobra.Command{
+	Use:          "sps-storm",
+	Short:        "Self-adaptive system for Apache Storm",
+	SilenceUsage: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := bindFlags(cmd); err != nil {
+			return err
+		}
+		return util.LoadConfig(configFile)
+	},
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "config file (default is configs/config.yaml)")
+	rootCmd.PersistentFlags().String("csv", "", "folder where the statistics are saved")
+	rootCmd.PersistentFlags().Bool("dry-run", false, "run the whole pipeline without updating the replicas of the topology")
+}
+
+func Execute() {
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// flagKeys maps every command-line flag that overrides the config file to its config key.
+var flagKeys = map[string]string{
+	"csv":            "storm.csv",
+	"dry-run":        "dry_run",
+	"duration":       "storm.deploy.duration",
+	"script":         "storm.deploy.script",
+	"dataset":        "storm.deploy.dataset",
+	"analyze":        "storm.deploy.analyze",
+	"window":         "storm.adaptive.time_window_size",
+	"model":          "storm.adaptive.predictive_model",
+	"samples":        "storm.adaptive.prediction_samples",
+	"predictions":    "storm.adaptive.prediction_number",
+	"limit-replicas": "storm.adaptive.limit_replicas",
+	"preset":         "storm.adaptive.preset",
+	"trace":          "simulator.trace",
+	"kind":           "workload.kind",
+	"length":         "workload.length",
+	"seed":           "workload.seed",
+}
+
+// bindFlags binds the flags of the running command to their config keys. The binding is done once the
+// command is chosen, since several commands expose flags for the same key and viper keeps one flag per key.
+func bindFlags(cmd *cobra.Command) error {
+	for name, key := range flagKeys {
+		if flag := cmd.Flags().Lookup(name); flag != nil {
+			if err := viper.BindPFlag(key, flag); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
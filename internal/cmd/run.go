@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"github.com/dwladdimiroc/sps-storm/internal/adaptive"
+	"github.com/dwladdimiroc/sps-storm/internal/api"
+	"github.com/dwladdimiroc/sps-storm/internal/app"
+	"github.com/dwladdimiroc/sps-storm/internal/control"
+	"github.com/dwladdimiroc/sps-storm/internal/kubernetes"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Deploy the application and run the self-adaptive system",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cluster, err := newCluster()
+		if err != nil {
+			return err
+		}
+
+		//Deploy app
+		topologyId := app.Deploy(cluster)
+
+		//Execute adaptive
+		controller := adaptive.New(cluster, topologyId)
+		go reloadOnHangup(controller)
+		if viper.GetBool("features.external_metrics") {
+			go kubernetes.ServeExternalMetrics(controller)
+		}
+		if viper.GetBool("features.control_api") {
+			go control.Serve(controller)
+		}
+		if viper.GetBool("features.inspection_api") {
+			go api.Serve(controller)
+		}
+		// The signals are handled from here on. Until then nothing reads the context, so the deploy and the
+		// creation of the topology, which wait for the cluster, are left to the default handler of SIGINT
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		controller.Start(ctx, time.Duration(viper.GetInt("storm.deploy.duration"))*time.Minute)
+		controller.Stop()
+		return nil
+	},
+}
+
+// reloadOnHangup reads the config file again on each SIGHUP, and reloads the parameters of the controller.
+func reloadOnHangup(controller *adaptive.Controller) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		if err := viper.ReadInConfig(); err != nil {
+			log.Printf("config: reload error={%v}\n", err)
+		} else {
+			controller.Reload()
+		}
+	}
+}
+
+func init() {
+	runCmd.Flags().Int("duration", 0, "time of the experiment (minutes)")
+	runCmd.Flags().String("script", "", "app script that Storm will deploy")
+	runCmd.Flags().String("dataset", "", "dataset used by the app script")
+	runCmd.Flags().Bool("analyze", true, "adapt the Storm application")
+	runCmd.Flags().Int("window", 0, "size of the monitor time window (seconds)")
+	runCmd.Flags().String("model", "", "model used by input prediction")
+	runCmd.Flags().Int("samples", 0, "number of samples used by the predictive model")
+	runCmd.Flags().Int("predictions", 0, "number of predictions made by the predictive model")
+	runCmd.Flags().Int("limit-replicas", 0, "limit of number of pool replicas")
+	runCmd.Flags().String("preset", "", "preset of the adaptive parameters (conservative, balanced, aggressive)")
+	rootCmd.AddCommand(runCmd)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/experiment"
+	"github.com/spf13/cobra"
+)
+
+var runScenarioCmd = &cobra.Command{
+	Use:   "run-scenario <file>",
+	Short: "Run the experiment described by a scenario file through the simulator",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		scenario, err := experiment.ReadScenario(args[0])
+		if err != nil {
+			return err
+		}
+		if repetitions, _ := cmd.Flags().GetInt("repetitions"); repetitions > 0 {
+			scenario.Repetitions = repetitions
+		}
+		results, err := scenario.Run()
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			fmt.Printf("scenario: run=%s,windows=%d,replica_hours=%.3f,sla_violations=%d,latency_avg=%.2f,rebalances=%d\n",
+				result.Run, result.Windows, result.ReplicaHours, result.SlaViolations, result.LatencyAvg, result.Rebalances)
+		}
+		fmt.Printf("scenario: output=%s,report=%s/report.md\n", scenario.Output, scenario.Output)
+		return nil
+	},
+}
+
+func init() {
+	runScenarioCmd.Flags().Int("repetitions", 0, "number of repetitions of each run, with consecutive seeds (default is the repetitions of the scenario)")
+	rootCmd.AddCommand(runScenarioCmd)
+}
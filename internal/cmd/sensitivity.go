@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/experiment"
+	"github.com/dwladdimiroc/sps-storm/internal/simulator"
+	"github.com/jszwec/csvutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"io"
+	"log"
+	"os"
+)
+
+var sensitivityCmd = &cobra.Command{
+	Use:   "sensitivity [topology-id]",
+	Short: "Sample combinations of the adaptive parameters and report how sensitive the replicas are to them",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n, _ := cmd.Flags().GetInt("combinations")
+		seed, _ := cmd.Flags().GetInt64("seed")
+		headroom, _ := cmd.Flags().GetFloat64Slice("headroom")
+		cooldown, _ := cmd.Flags().GetIntSlice("cooldown")
+		planning, _ := cmd.Flags().GetIntSlice("planning")
+		output, _ := cmd.Flags().GetString("output")
+		if len(headroom) != 2 || len(cooldown) != 2 || len(planning) != 2 {
+			return fmt.Errorf("sensitivity: every range needs a min and a max")
+		}
+
+		var trace []int64
+		var err error
+		if len(args) == 1 {
+			trace, err = simulator.ReadTrace(viper.GetString("storm.csv") + "/" + args[0] + "/Topology.csv")
+		} else {
+			trace, err = experiment.ReadTrace()
+		}
+		if err != nil {
+			return fmt.Errorf("sensitivity: %v", err)
+		}
+
+		if verbose, _ := cmd.Flags().GetBool("verbose"); !verbose {
+			log.SetOutput(io.Discard)
+			defer log.SetOutput(os.Stderr)
+		}
+		viper.Set("features.exporters", false)
+		ranges := experiment.Ranges{
+			Headroom:          [2]float64{headroom[0], headroom[1]},
+			ScaleDownCooldown: [2]int{cooldown[0], cooldown[1]},
+			PlanningSamples:   [2]int{planning[0], planning[1]},
+		}
+		samples, correlation, err := experiment.Sensitivity(trace, n, seed, ranges)
+		if err != nil {
+			return fmt.Errorf("sensitivity: %v", err)
+		}
+
+		w := os.Stdout
+		if output != "" {
+			if f, err := os.Create(output); err != nil {
+				return fmt.Errorf("sensitivity: %v", err)
+			} else {
+				defer f.Close()
+				w = f
+			}
+		}
+		if b, err := csvutil.Marshal(samples); err != nil {
+			return fmt.Errorf("sensitivity: %v", err)
+		} else if _, err := w.Write(b); err != nil {
+			return fmt.Errorf("sensitivity: %v", err)
+		}
+		for _, parameter := range []string{"headroom", "scale_down_cooldown", "planning_samples"} {
+			fmt.Printf("# correlation of %s with the distance: %.3f\n", parameter, correlation[parameter])
+		}
+		return nil
+	},
+}
+
+func init() {
+	sensitivityCmd.Flags().Int("combinations", 50, "number of sampled combinations")
+	sensitivityCmd.Flags().Int64("seed", 1, "seed of the sampling")
+	sensitivityCmd.Flags().Float64Slice("headroom", []float64{0, 0.5}, "range of storm.adaptive.headroom")
+	sensitivityCmd.Flags().IntSlice("cooldown", []int{0, 5}, "range of storm.adaptive.scale_down_cooldown")
+	sensitivityCmd.Flags().IntSlice("planning", []int{2, 10}, "range of storm.adaptive.planning_samples")
+	sensitivityCmd.Flags().StringP("output", "o", "", "file of the CSV (default is stdout)")
+	sensitivityCmd.Flags().Bool("verbose", false, "keep the log of the adaptive system")
+	rootCmd.AddCommand(sensitivityCmd)
+}
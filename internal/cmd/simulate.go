@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/experiment"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Run the self-adaptive system against a simulated topology fed with an input-rate trace",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		trace, err := experiment.ReadTrace()
+		if err != nil {
+			return fmt.Errorf("simulate: %v", err)
+		}
+
+		topologyId := "simulation-" + time.Now().Format("20060102-150405")
+		summary, err := experiment.Simulate(topologyId, trace)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("simulate: topology=%s,windows=%d,replica_hours=%.3f,saving=%.3f,sla_violations=%d,latency_avg=%.2f,latency_p99=%.2f,degradation=%.3f,sla_score=%.3f,rebalances=%d,cost=%.4f\n",
+			topologyId, summary.Windows, summary.ReplicaHours, summary.Saving, summary.SlaViolations, summary.LatencyAvg, summary.LatencyP99, summary.DegradationAvg, summary.SlaScoreAvg, summary.Rebalances, summary.Cost)
+		return nil
+	},
+}
+
+func init() {
+	simulateCmd.Flags().String("trace", "", "input-rate trace of the simulation (default is the synthetic trace of the workload section)")
+	simulateCmd.Flags().Bool("analyze", true, "adapt the simulated topology")
+	simulateCmd.Flags().Int("window", 0, "size of the monitor time window (seconds)")
+	simulateCmd.Flags().String("model", "", "model used by input prediction")
+	simulateCmd.Flags().Int("samples", 0, "number of samples used by the predictive model")
+	simulateCmd.Flags().Int("predictions", 0, "number of predictions made by the predictive model")
+	simulateCmd.Flags().Int("limit-replicas", 0, "limit of number of pool replicas")
+	simulateCmd.Flags().String("preset", "", "preset of the adaptive parameters (conservative, balanced, aggressive)")
+	rootCmd.AddCommand(simulateCmd)
+}
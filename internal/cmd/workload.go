@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/experiment"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+var workloadCmd = &cobra.Command{
+	Use:   "workload",
+	Short: "Generate synthetic input-rate traces",
+}
+
+var workloadOutput string
+
+var workloadGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Write the trace of the workload section, one rate per line",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		trace, err := experiment.GenerateWorkload()
+		if err != nil {
+			return err
+		}
+
+		var w io.Writer = os.Stdout
+		if workloadOutput != "" {
+			f, err := os.Create(workloadOutput)
+			if err != nil {
+				return fmt.Errorf("workload: %v", err)
+			}
+			defer f.Close()
+			w = f
+		}
+		for _, rate := range trace {
+			if _, err := fmt.Fprintln(w, rate); err != nil {
+				return fmt.Errorf("workload: %v", err)
+			}
+		}
+		return nil
+	},
+}
+
+var workloadKey string
+
+var workloadDriveCmd = &cobra.Command{
+	Use:   "drive",
+	Short: "Publish the rate of the trace in Redis every time window, for a spout that emits at that rate",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		trace, err := experiment.GenerateWorkload()
+		if err != nil {
+			return err
+		}
+
+		ticker := time.NewTicker(time.Duration(viper.GetInt("storm.adaptive.time_window_size")) * time.Second)
+		defer ticker.Stop()
+		for t, rate := range trace {
+			if err := util.RedisSet(workloadKey, strconv.FormatInt(rate, 10)); err != nil {
+				return fmt.Errorf("workload drive: %v", err)
+			}
+			log.Printf("[t=%d] workload drive: key={%s},rate={%d}\n", t, workloadKey, rate)
+			<-ticker.C
+		}
+		return nil
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{workloadGenerateCmd, workloadDriveCmd} {
+		c.Flags().String("kind", "", "kind of synthetic trace (constant, sinusoidal, step, poisson, flash_crowd, seasonal)")
+		c.Flags().Int("length", 0, "number of time windows of the trace")
+		c.Flags().Int64("seed", 0, "seed of the random numbers of the trace")
+	}
+	workloadGenerateCmd.Flags().StringVarP(&workloadOutput, "output", "o", "", "output file (default is stdout)")
+	workloadDriveCmd.Flags().StringVar(&workloadKey, "key", "input_rate", "Redis key where the rate is published")
+	workloadCmd.AddCommand(workloadGenerateCmd)
+	workloadCmd.AddCommand(workloadDriveCmd)
+	rootCmd.AddCommand(workloadCmd)
+}
@@ -0,0 +1,167 @@
+package control
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// role is what a caller of the control API may do: a viewer reads the snapshots of the topology, and an operator
+// also acts on it
+type role int
+
+const (
+	viewer role = iota
+	operator
+)
+
+func (r role) String() string {
+	if r == operator {
+		return "operator"
+	}
+	return "viewer"
+}
+
+// viewerMethods are the methods of the control API open to viewers; the rest need an operator
+var viewerMethods = map[string]bool{
+	"/sps.v1.Control/Subscribe":     true,
+	"/sps.v1.Control/StreamMetrics": true,
+}
+
+// authenticator checks the bearer token in the authorization metadata of each call of the control API, and the
+// role it grants: the static token of control_api.token (or control_api.token_file) is an operator and the one
+// of control_api.viewer_token a viewer, and a JWT of the OIDC issuer control_api.oidc.issuer is an operator when
+// the claim control_api.oidc.roles_claim holds operator, and a viewer otherwise. With no token nor issuer, every
+// call is accepted.
+type authenticator struct {
+	token       string
+	viewerToken string
+	oidc        *oidcVerifier
+	rolesClaim  string
+}
+
+func newAuthenticator() (*authenticator, error) {
+	a := &authenticator{
+		token:       viper.GetString("control_api.token"),
+		viewerToken: viper.GetString("control_api.viewer_token"),
+		rolesClaim:  viper.GetString("control_api.oidc.roles_claim"),
+	}
+	if file := viper.GetString("control_api.token_file"); file != "" {
+		if b, err := os.ReadFile(file); err != nil {
+			return nil, fmt.Errorf("control api: token file: %v", err)
+		} else {
+			a.token = strings.TrimSpace(string(b))
+		}
+	}
+	if issuer := viper.GetString("control_api.oidc.issuer"); issuer != "" {
+		a.oidc = newOidcVerifier(issuer, viper.GetString("control_api.oidc.audience"), time.Duration(viper.GetInt("control_api.oidc.leeway"))*time.Second)
+	}
+	return a, nil
+}
+
+func (a *authenticator) enabled() bool {
+	return a.token != "" || a.viewerToken != "" || a.oidc != nil
+}
+
+// authorize authenticates the caller of the method, and checks that its role may call it.
+func (a *authenticator) authorize(ctx context.Context, method string) error {
+	if !a.enabled() {
+		return nil
+	}
+	r, err := a.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+	if r != operator && !viewerMethods[method] {
+		return status.Errorf(codes.PermissionDenied, "%s needs the operator role, got %s", method, r)
+	}
+	return nil
+}
+
+// authenticate returns the role of the bearer token of the call.
+func (a *authenticator) authenticate(ctx context.Context) (role, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	for _, value := range md.Get("authorization") {
+		if t, ok := bearer(value); ok {
+			token = t
+		}
+	}
+	return a.tokenRole(token)
+}
+
+// tokenRole returns the role of the bearer token.
+func (a *authenticator) tokenRole(token string) (role, error) {
+	if token == "" {
+		return viewer, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+	if a.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) == 1 {
+		return operator, nil
+	}
+	if a.viewerToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.viewerToken)) == 1 {
+		return viewer, nil
+	}
+	if a.oidc != nil {
+		claims, err := a.oidc.verify(token)
+		if err != nil {
+			return viewer, status.Errorf(codes.Unauthenticated, "token: %v", err)
+		}
+		if hasValue(claims[a.rolesClaim], operator.String()) {
+			return operator, nil
+		}
+		return viewer, nil
+	}
+	return viewer, status.Error(codes.Unauthenticated, "invalid bearer token")
+}
+
+// bearer returns the token of an authorization header with the bearer scheme.
+func bearer(authorization string) (string, bool) {
+	if scheme, credentials, ok := strings.Cut(authorization, " "); ok && strings.EqualFold(scheme, "bearer") {
+		return strings.TrimSpace(credentials), true
+	}
+	return "", false
+}
+
+// RequireViewer wraps the handler of a read-only HTTP API, so it requires the same bearer tokens as the control
+// API, any role. With no token nor issuer configured, every request is accepted.
+func RequireViewer(next http.Handler) (http.Handler, error) {
+	a, err := newAuthenticator()
+	if err != nil {
+		return nil, err
+	}
+	if !a.enabled() {
+		return next, nil
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := bearer(r.Header.Get("Authorization"))
+		if _, err := a.tokenRole(token); err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, status.Convert(err).Message(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}), nil
+}
+
+func (a *authenticator) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := a.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *authenticator) stream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.authorize(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
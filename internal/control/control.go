@@ -0,0 +1,247 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/adaptive"
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"log"
+	"net"
+)
+
+// The control API of api/control.proto, written by hand since its messages are the well-known types of
+// protobuf, so there is no code to generate.
+type controlServer interface {
+	Subscribe(*emptypb.Empty, grpc.ServerStream) error
+	StreamMetrics(*emptypb.Empty, grpc.ServerStream) error
+	SubmitPlan(context.Context, *structpb.Struct) (*emptypb.Empty, error)
+	Resume(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "sps.v1.Control",
+	HandlerType: (*controlServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "SubmitPlan", Handler: submitPlanHandler},
+		{MethodName: "Resume", Handler: resumeHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "Subscribe", Handler: subscribeHandler, ServerStreams: true},
+		{StreamName: "StreamMetrics", Handler: streamMetricsHandler, ServerStreams: true},
+	},
+	Metadata: "api/control.proto",
+}
+
+// Serve runs the gRPC server of the control API on control_api.port, requiring a bearer token when one is
+// configured and limiting the rate of the calls that act on the topology, which are the calls of the controller.
+func Serve(controller *adaptive.Controller) {
+	auth, err := newAuthenticator()
+	if err != nil {
+		log.Printf("control api: error={%v}\n", err)
+		return
+	}
+	if !auth.enabled() {
+		log.Printf("control api: no authentication, set control_api.token, control_api.viewer_token or control_api.oidc.issuer\n")
+	}
+	addr := ":" + viper.GetString("control_api.port")
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Printf("control api: error={%v}\n", err)
+		return
+	}
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(auth.unary, newLimiter().unary), grpc.StreamInterceptor(auth.stream))
+	server.RegisterService(&serviceDesc, &control{controller: controller})
+	log.Printf("control api: init,addr={%s}\n", addr)
+	if err := server.Serve(listener); err != nil {
+		log.Printf("control api: error={%v}\n", err)
+	}
+}
+
+type control struct {
+	controller *adaptive.Controller
+}
+
+func (c *control) Subscribe(_ *emptypb.Empty, stream grpc.ServerStream) error {
+	snapshots, cancel := c.controller.Subscribe()
+	defer cancel()
+	log.Printf("control api: subscribe\n")
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case topology := <-snapshots:
+			if msg, err := snapshotStruct(topology); err != nil {
+				return status.Errorf(codes.Internal, "snapshot: %v", err)
+			} else if err := stream.SendMsg(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// StreamMetrics streams every collection of the monitor, in order. A collector that falls behind is disconnected
+// with RESOURCE_EXHAUSTED, and the sequence of the collections tells it what it missed.
+func (c *control) StreamMetrics(_ *emptypb.Empty, stream grpc.ServerStream) error {
+	collections, cancel := c.controller.Collect()
+	defer cancel()
+	log.Printf("control api: stream metrics\n")
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case collection, ok := <-collections:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "stream metrics: collector fell behind")
+			}
+			if msg, err := collectionStruct(collection); err != nil {
+				return status.Errorf(codes.Internal, "collection: %v", err)
+			} else if err := stream.SendMsg(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (c *control) SubmitPlan(_ context.Context, msg *structpb.Struct) (*emptypb.Empty, error) {
+	replicas, err := planReplicas(msg)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := c.controller.SubmitPlan(replicas); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	log.Printf("control api: plan={%v}\n", replicas)
+	return &emptypb.Empty{}, nil
+}
+
+func (c *control) Resume(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	if err := c.controller.Resume(); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	log.Printf("control api: resume\n")
+	return &emptypb.Empty{}, nil
+}
+
+func subscribeHandler(srv interface{}, stream grpc.ServerStream) error {
+	in := new(emptypb.Empty)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	return srv.(controlServer).Subscribe(in, stream)
+}
+
+func streamMetricsHandler(srv interface{}, stream grpc.ServerStream) error {
+	in := new(emptypb.Empty)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	return srv.(controlServer).StreamMetrics(in, stream)
+}
+
+func submitPlanHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(structpb.Struct)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(controlServer).SubmitPlan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/sps.v1.Control/SubmitPlan"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(controlServer).SubmitPlan(ctx, req.(*structpb.Struct))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func resumeHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(controlServer).Resume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/sps.v1.Control/Resume"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(controlServer).Resume(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func snapshotStruct(topology storm.Topology) (*structpb.Struct, error) {
+	var bolts []interface{}
+	for _, bolt := range topology.Bolts {
+		bolts = append(bolts, map[string]interface{}{
+			"name":                        bolt.Name,
+			"replicas":                    bolt.Replicas,
+			"prediction_replicas":         bolt.PredictionReplicas,
+			"input":                       bolt.Input,
+			"output":                      bolt.Output,
+			"queue":                       bolt.Queue,
+			"executed_time_avg":           bolt.ExecutedTimeAvg,
+			"executed_time_benchmark_avg": bolt.ExecutedTimeBenchmarkAvg,
+			"gpu_utilization":             bolt.GpuUtilization,
+			"gpu_memory":                  bolt.GpuMemory,
+		})
+	}
+	return structpb.NewStruct(map[string]interface{}{
+		"id":                   topology.Id,
+		"time":                 topology.Time,
+		"profile":              topology.Profile,
+		"state":                topology.State,
+		"input_rate":           topology.InputRateT,
+		"predicted_input_rate": topology.PredictedInputRateT,
+		"latency":              topology.Latency,
+		"cost":                 topology.Cost,
+		"lag":                  topology.Lag,
+		"throughput":           topology.Throughput,
+		"degradation":          topology.Degradation,
+		"sla_score":            topology.SlaScore,
+		"bolts":                bolts,
+	})
+}
+
+// collectionStruct returns the collection as {"sequence", "period", "topology": <snapshot>, "metrics": <metrics of
+// the cluster, as the JSON of the Storm UI>}.
+func collectionStruct(collection adaptive.Collection) (*structpb.Struct, error) {
+	topology, err := snapshotStruct(collection.Topology)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(collection.Metrics)
+	if err != nil {
+		return nil, err
+	}
+	var metrics map[string]interface{}
+	if err := json.Unmarshal(b, &metrics); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(map[string]interface{}{
+		"sequence": collection.Sequence,
+		"period":   collection.Period,
+		"topology": topology.AsMap(),
+		"metrics":  metrics,
+	})
+}
+
+func planReplicas(msg *structpb.Struct) (map[string]int64, error) {
+	field, ok := msg.GetFields()["replicas"]
+	if !ok || field.GetStructValue() == nil {
+		return nil, fmt.Errorf("plan: missing replicas")
+	}
+	replicas := make(map[string]int64)
+	for bolt, value := range field.GetStructValue().GetFields() {
+		if _, ok := value.GetKind().(*structpb.Value_NumberValue); !ok {
+			return nil, fmt.Errorf("plan: replicas of bolt %s is not a number", bolt)
+		}
+		replicas[bolt] = int64(value.GetNumberValue())
+	}
+	return replicas, nil
+}
@@ -0,0 +1,88 @@
+package control
+
+import (
+	"context"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"sync"
+	"time"
+)
+
+// limiter limits the calls of each client (by host) to the methods of the operators with a token bucket: up to
+// control_api.rate_limit.burst calls at once, refilled at control_api.rate_limit.per_minute, so a client in a
+// loop cannot flood the topology with plans. The methods of the viewers are not limited.
+type limiter struct {
+	rate  float64 // calls per second
+	burst float64
+
+	lock    sync.Mutex
+	buckets map[string]*bucket // by client
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter() *limiter {
+	return &limiter{
+		rate:    viper.GetFloat64("control_api.rate_limit.per_minute") / 60,
+		burst:   viper.GetFloat64("control_api.rate_limit.burst"),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow takes a call from the bucket of the client, when it has one left.
+func (l *limiter) allow(client string, now time.Time) bool {
+	if l.rate <= 0 {
+		return true
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	// the buckets refilled since then are full, as a new one
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	for c, b := range l.buckets {
+		if now.Sub(b.last) > full {
+			delete(l.buckets, c)
+		}
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (l *limiter) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !viewerMethods[info.FullMethod] && !l.allow(client(ctx), time.Now()) {
+		return nil, status.Errorf(codes.ResourceExhausted, "%s: rate limit of %.0f calls per minute", info.FullMethod, l.rate*60)
+	}
+	return handler(ctx, req)
+}
+
+// client returns the host of the caller.
+func client(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	return p.Addr.String()
+}
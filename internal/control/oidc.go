@@ -0,0 +1,219 @@
+package control
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oidcRefresh is the least time between two reads of the keys of the issuer, so tokens signed with unknown keys do
+// not flood it
+const oidcRefresh = time.Minute
+
+// oidcVerifier verifies the JWTs signed by an OIDC issuer with RS256: the signature with the keys published in the
+// jwks_uri of its discovery document, the issuer, the audience (when set), and the expiration, the not before and
+// the issued at times, allowing leeway of clock skew.
+type oidcVerifier struct {
+	issuer   string
+	audience string
+	leeway   time.Duration
+	client   *http.Client
+
+	lock    sync.Mutex
+	keys    map[string]*rsa.PublicKey // by kid
+	fetched time.Time
+	// fetching is closed when the read of the keys in progress completes, so the tokens with an unknown key wait
+	// for it instead of reading the keys again; nil when none is in progress
+	fetching chan struct{}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func newOidcVerifier(issuer, audience string, leeway time.Duration) *oidcVerifier {
+	return &oidcVerifier{
+		issuer:   strings.TrimSuffix(issuer, "/"),
+		audience: audience,
+		leeway:   leeway,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		keys:     make(map[string]*rsa.PublicKey),
+	}
+}
+
+// verify returns the claims of the token when it is valid.
+func (v *oidcVerifier) verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed jwt")
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported alg %s", header.Alg)
+	}
+	key, err := v.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("signature: %v", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("signature: %v", err)
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != v.issuer {
+		return nil, fmt.Errorf("issuer %s", iss)
+	}
+	now := time.Now()
+	if exp, ok := claims["exp"].(float64); !ok || now.After(time.Unix(int64(exp), 0).Add(v.leeway)) {
+		return nil, fmt.Errorf("expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.leeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, fmt.Errorf("not valid yet")
+	}
+	if iat, ok := claims["iat"].(float64); ok && now.Add(v.leeway).Before(time.Unix(int64(iat), 0)) {
+		return nil, fmt.Errorf("issued in the future")
+	}
+	if v.audience != "" && !hasValue(claims["aud"], v.audience) {
+		return nil, fmt.Errorf("audience")
+	}
+	return claims, nil
+}
+
+// key returns the key of the kid, reading the keys of the issuer again when it is unknown. The keys are read
+// without the lock, so the tokens of known keys are verified meanwhile, and by one caller at a time: the others
+// wait for its keys.
+func (v *oidcVerifier) key(kid string) (*rsa.PublicKey, error) {
+	v.lock.Lock()
+	for v.fetching != nil {
+		if key, ok := v.keys[kid]; ok {
+			v.lock.Unlock()
+			return key, nil
+		}
+		fetching := v.fetching
+		v.lock.Unlock()
+		<-fetching
+		v.lock.Lock()
+	}
+	if key, ok := v.keys[kid]; ok {
+		v.lock.Unlock()
+		return key, nil
+	}
+	if time.Since(v.fetched) < oidcRefresh {
+		v.lock.Unlock()
+		return nil, fmt.Errorf("unknown key %s", kid)
+	}
+	v.fetched = time.Now()
+	fetching := make(chan struct{})
+	v.fetching = fetching
+	v.lock.Unlock()
+
+	keys, err := v.fetchKeys()
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.fetching = nil
+	close(fetching)
+	if err != nil {
+		return nil, fmt.Errorf("keys: %v", err)
+	}
+	v.keys = keys
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key %s", kid)
+}
+
+func (v *oidcVerifier) fetchKeys() (map[string]*rsa.PublicKey, error) {
+	var discovery struct {
+		JwksUri string `json:"jwks_uri"`
+	}
+	if err := v.get(v.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := v.get(discovery.JwksUri, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	return keys, nil
+}
+
+func (v *oidcVerifier) get(url string, out interface{}) error {
+	res, err := v.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("get %s: %s", url, res.Status)
+	}
+	return json.Unmarshal(data, out)
+}
+
+func decodeSegment(segment string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("malformed jwt: %v", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("malformed jwt: %v", err)
+	}
+	return nil
+}
+
+// hasValue reports whether the claim, a string or a list of them (as aud), holds the value.
+func hasValue(claim interface{}, value string) bool {
+	switch claim := claim.(type) {
+	case string:
+		return claim == value
+	case []interface{}:
+		for _, c := range claim {
+			if c == value {
+				return true
+			}
+		}
+	}
+	return false
+}
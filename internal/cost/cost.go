@@ -0,0 +1,140 @@
+package cost
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/spf13/viper"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ReplicaHourPrice returns the price of a replica of a bolt during an hour, in cost.currency: the price of an
+// instance over the replicas that fit in it (cost.replicas_per_instance). The price of the instance comes from
+// cost.provider: static (cost.replica_hour is already the price of a replica), aws (the Price List API of AWS,
+// through the aws command) or gcp (the Cloud Billing Catalog API).
+func ReplicaHourPrice() (float64, error) {
+	switch provider := viper.GetString("cost.provider"); provider {
+	case "static":
+		return viper.GetFloat64("cost.replica_hour"), nil
+	case "aws":
+		if price, err := awsInstancePrice(); err != nil {
+			return 0, err
+		} else {
+			return price / viper.GetFloat64("cost.replicas_per_instance"), nil
+		}
+	case "gcp":
+		if price, err := gcpSkuPrice(); err != nil {
+			return 0, err
+		} else {
+			return price / viper.GetFloat64("cost.replicas_per_instance"), nil
+		}
+	default:
+		return 0, fmt.Errorf("cost: unknown provider %q", provider)
+	}
+}
+
+type awsProduct struct {
+	Terms struct {
+		OnDemand map[string]struct {
+			PriceDimensions map[string]struct {
+				Unit         string            `json:"unit"`
+				PricePerUnit map[string]string `json:"pricePerUnit"`
+			} `json:"priceDimensions"`
+		} `json:"OnDemand"`
+	} `json:"terms"`
+}
+
+// awsInstancePrice returns the on-demand price per hour of cost.aws.instance_type, Linux and shared tenancy, in
+// cost.aws.region.
+func awsInstancePrice() (float64, error) {
+	filters := []string{
+		"Type=TERM_MATCH,Field=instanceType,Value=" + viper.GetString("cost.aws.instance_type"),
+		"Type=TERM_MATCH,Field=regionCode,Value=" + viper.GetString("cost.aws.region"),
+		"Type=TERM_MATCH,Field=operatingSystem,Value=Linux",
+		"Type=TERM_MATCH,Field=tenancy,Value=Shared",
+		"Type=TERM_MATCH,Field=preInstalledSw,Value=NA",
+		"Type=TERM_MATCH,Field=capacitystatus,Value=Used",
+	}
+	args := append([]string{"pricing", "get-products", "--region", "us-east-1", "--service-code", "AmazonEC2", "--output", "json", "--filters"}, filters...)
+	output := util.Execute("aws", args, "")
+
+	var response struct {
+		PriceList []string `json:"PriceList"`
+	}
+	if err := json.Unmarshal([]byte(output), &response); err != nil {
+		return 0, fmt.Errorf("cost aws: %v", err)
+	}
+	for _, item := range response.PriceList {
+		var product awsProduct
+		if err := json.Unmarshal([]byte(item), &product); err != nil {
+			return 0, fmt.Errorf("cost aws: %v", err)
+		}
+		for _, term := range product.Terms.OnDemand {
+			for _, dimension := range term.PriceDimensions {
+				if dimension.Unit == "Hrs" {
+					return strconv.ParseFloat(dimension.PricePerUnit[viper.GetString("cost.currency")], 64)
+				}
+			}
+		}
+	}
+	return 0, fmt.Errorf("cost aws: no on-demand price of %s in %s", viper.GetString("cost.aws.instance_type"), viper.GetString("cost.aws.region"))
+}
+
+type gcpSkus struct {
+	Skus []struct {
+		SkuId       string `json:"skuId"`
+		PricingInfo []struct {
+			PricingExpression struct {
+				UsageUnit   string `json:"usageUnit"`
+				TieredRates []struct {
+					UnitPrice struct {
+						Units string `json:"units"`
+						Nanos int64  `json:"nanos"`
+					} `json:"unitPrice"`
+				} `json:"tieredRates"`
+			} `json:"pricingExpression"`
+		} `json:"pricingInfo"`
+	} `json:"skus"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// gcpSkuPrice returns the price per hour of cost.gcp.sku in the service cost.gcp.service (Compute Engine by
+// default), the price of a whole instance.
+func gcpSkuPrice() (float64, error) {
+	var pageToken string
+	for {
+		query := url.Values{"key": {viper.GetString("cost.gcp.api_key")}, "currencyCode": {viper.GetString("cost.currency")}, "pageToken": {pageToken}}
+		res, err := http.Get("https://cloudbilling.googleapis.com/v1/services/" + viper.GetString("cost.gcp.service") + "/skus?" + query.Encode())
+		if err != nil {
+			return 0, fmt.Errorf("cost gcp: %v", err)
+		}
+		data, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return 0, fmt.Errorf("cost gcp: %s", res.Status)
+		}
+
+		var skus gcpSkus
+		if err := json.Unmarshal(data, &skus); err != nil {
+			return 0, fmt.Errorf("cost gcp: %v", err)
+		}
+		for _, sku := range skus.Skus {
+			if sku.SkuId != viper.GetString("cost.gcp.sku") || len(sku.PricingInfo) == 0 {
+				continue
+			}
+			expression := sku.PricingInfo[0].PricingExpression
+			if expression.UsageUnit != "h" || len(expression.TieredRates) == 0 {
+				return 0, fmt.Errorf("cost gcp: sku %s is not priced by hour", sku.SkuId)
+			}
+			price := expression.TieredRates[len(expression.TieredRates)-1].UnitPrice
+			units, _ := strconv.ParseFloat(price.Units, 64)
+			return units + float64(price.Nanos)/1e9, nil
+		}
+		if pageToken = skus.NextPageToken; pageToken == "" {
+			return 0, fmt.Errorf("cost gcp: sku %s not found", viper.GetString("cost.gcp.sku"))
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package experiment
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jszwec/csvutil"
+	"github.com/spf13/viper"
+	"os"
+	"strings"
+)
+
+// Golden runs the first model and preset of the scenario on the logical clock, and compares the decisions of the
+// planning with the golden file. It returns the differences, one per line, or writes the golden file when update
+// is set.
+func Golden(s *Scenario, golden string, update bool) ([]string, error) {
+	if err := s.Apply(); err != nil {
+		return nil, err
+	}
+	trace, err := s.ReadTrace()
+	if err != nil {
+		return nil, fmt.Errorf("golden: %v", err)
+	}
+	viper.Set("features.exporters", false)
+	viper.Set("storm.adaptive.predictive_model", s.Models[0])
+	if err := ApplyPreset(s.Presets[0]); err != nil {
+		return nil, fmt.Errorf("golden: %v", err)
+	}
+	controller, err := simulate("golden-"+s.Name, trace)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := csvutil.Marshal(controller.GetDecisions())
+	if err != nil {
+		return nil, fmt.Errorf("golden: %v", err)
+	}
+	if update {
+		return nil, os.WriteFile(golden, b, 0644)
+	}
+
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		return nil, fmt.Errorf("golden: %v", err)
+	}
+	if bytes.Equal(expected, b) {
+		return nil, nil
+	}
+	var diffs []string
+	want, got := strings.Split(strings.TrimSpace(string(expected)), "\n"), strings.Split(strings.TrimSpace(string(b)), "\n")
+	for i := 0; i < len(want) || i < len(got); i++ {
+		var w, g string
+		if i < len(want) {
+			w = want[i]
+		}
+		if i < len(got) {
+			g = got[i]
+		}
+		if w != g {
+			diffs = append(diffs, fmt.Sprintf("line %d: golden={%s},got={%s}", i+1, w, g))
+		}
+	}
+	return diffs, nil
+}
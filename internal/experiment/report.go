@@ -0,0 +1,100 @@
+package experiment
+
+import (
+	"fmt"
+	"github.com/montanaflynn/stats"
+	"io"
+	"math"
+)
+
+// Metric is the mean of a metric over the repetitions of a run, with its 95% confidence interval.
+type Metric struct {
+	Mean float64
+	Std  float64
+	CI   float64
+}
+
+// Aggregated are the statistics of the repetitions of a model and a preset.
+type Aggregated struct {
+	Model         string
+	Preset        string
+	Repetitions   int
+	SlaViolations Metric
+	ReplicaHours  Metric
+	LatencyAvg    Metric
+	LatencyP99    Metric
+	Rebalances    Metric
+}
+
+// Aggregate groups the results by model and preset, in the order of the runs.
+func Aggregate(results []Result) []Aggregated {
+	var aggregated []Aggregated
+	groups := make(map[string][]Result)
+	for _, result := range results {
+		key := result.Model + "/" + result.Preset
+		if _, ok := groups[key]; !ok {
+			aggregated = append(aggregated, Aggregated{Model: result.Model, Preset: result.Preset})
+		}
+		groups[key] = append(groups[key], result)
+	}
+
+	for i := range aggregated {
+		group := groups[aggregated[i].Model+"/"+aggregated[i].Preset]
+		var violations, replicaHours, latency, latencyP99, rebalances []float64
+		for _, result := range group {
+			violations = append(violations, float64(result.SlaViolations))
+			replicaHours = append(replicaHours, result.ReplicaHours)
+			latency = append(latency, result.LatencyAvg)
+			latencyP99 = append(latencyP99, result.LatencyP99)
+			rebalances = append(rebalances, float64(result.Rebalances))
+		}
+		aggregated[i].Repetitions = len(group)
+		aggregated[i].SlaViolations = metric(violations)
+		aggregated[i].ReplicaHours = metric(replicaHours)
+		aggregated[i].LatencyAvg = metric(latency)
+		aggregated[i].LatencyP99 = metric(latencyP99)
+		aggregated[i].Rebalances = metric(rebalances)
+	}
+	return aggregated
+}
+
+// tCritical are the two-sided 95% critical values of the Student's t-distribution, indexed by degrees of freedom.
+var tCritical = []float64{0, 12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042}
+
+func metric(data []float64) Metric {
+	var m Metric
+	m.Mean, _ = stats.Mean(data)
+	if n := len(data); n > 1 {
+		m.Std, _ = stats.StandardDeviationSample(data)
+		t := 1.96
+		if n-1 < len(tCritical) {
+			t = tCritical[n-1]
+		}
+		m.CI = t * m.Std / math.Sqrt(float64(n))
+	}
+	return m
+}
+
+// WriteReport writes the statistics of a scenario as a markdown report.
+func WriteReport(w io.Writer, scenario *Scenario, aggregated []Aggregated) error {
+	if _, err := fmt.Fprintf(w, "# %s\n\n%s\n\nRepetitions: %d, windows: %d. Mean ± 95%% confidence interval.\n\n",
+		scenario.Name, scenario.Description, scenario.Repetitions, scenario.Windows); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w, "| model | preset | runs | SLA violations | replica-hours | latency (ms) | latency P99 (ms) | rebalances |\n|---|---|---|---|---|---|---|---|"); err != nil {
+		return err
+	}
+	for _, a := range aggregated {
+		if _, err := fmt.Fprintf(w, "| %s | %s | %d | %s | %s | %s | %s | %s |\n", a.Model, a.Preset, a.Repetitions,
+			a.SlaViolations, a.ReplicaHours, a.LatencyAvg, a.LatencyP99, a.Rebalances); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m Metric) String() string {
+	return fmt.Sprintf("%.3f ± %.3f", m.Mean, m.CI)
+}
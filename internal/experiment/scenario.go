@@ -0,0 +1,195 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/adaptive"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/jszwec/csvutil"
+	"github.com/spf13/viper"
+	"log"
+	"os"
+)
+
+// Scenario describes a reproducible experiment: the input-rate trace (a file, or a synthetic workload), the shape
+// of the simulated topology, the predictive models and presets compared, the duration and the output folder.
+// The section config overrides any key of the config file.
+type Scenario struct {
+	Name        string
+	Description string
+	Output      string
+	Windows     int
+	Repetitions int
+	Trace       string
+	Models      []string
+	Presets     []string
+
+	file *viper.Viper
+}
+
+// Result is the summary of a run of a scenario.
+type Result struct {
+	Scenario string `csv:"scenario"`
+	Run      string `csv:"run"`
+	Model    string `csv:"model"`
+	Preset   string `csv:"preset"`
+	Seed     int64  `csv:"seed"`
+	adaptive.Summary
+}
+
+// ReadScenario reads a scenario file.
+func ReadScenario(filename string) (*Scenario, error) {
+	file := viper.New()
+	file.SetConfigFile(filename)
+	if err := file.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("scenario: %v", err)
+	}
+
+	scenario := &Scenario{
+		Name:        file.GetString("name"),
+		Description: file.GetString("description"),
+		Output:      file.GetString("output"),
+		Windows:     file.GetInt("windows"),
+		Repetitions: file.GetInt("repetitions"),
+		Trace:       file.GetString("trace"),
+		Models:      file.GetStringSlice("models"),
+		Presets:     file.GetStringSlice("presets"),
+		file:        file,
+	}
+	if scenario.Name == "" {
+		return nil, fmt.Errorf("scenario %s: name is required", filename)
+	}
+	if scenario.Output == "" {
+		scenario.Output = viper.GetString("storm.csv") + "/" + scenario.Name
+	}
+	if scenario.Repetitions < 1 {
+		scenario.Repetitions = 1
+	}
+	if len(scenario.Models) == 0 {
+		scenario.Models = []string{viper.GetString("storm.adaptive.predictive_model")}
+	}
+	if len(scenario.Presets) == 0 {
+		scenario.Presets = []string{viper.GetString("storm.adaptive.preset")}
+	}
+	return scenario, nil
+}
+
+// Apply sets the keys of the scenario over the loaded config and validates the result.
+func (s *Scenario) Apply() error {
+	if config := s.file.Sub("config"); config != nil {
+		for _, key := range config.AllKeys() {
+			viper.Set(key, config.Get(key))
+		}
+	}
+	if workload := s.file.Sub("workload"); workload != nil {
+		for _, key := range workload.AllKeys() {
+			viper.Set("workload."+key, workload.Get(key))
+		}
+	}
+	if s.file.IsSet("topology.spout") {
+		viper.Set("simulator.spout", s.file.GetString("topology.spout"))
+	}
+	if s.file.IsSet("topology.bolts") {
+		viper.Set("simulator.bolts", s.file.Get("topology.bolts"))
+	}
+	viper.Set("simulator.trace", s.Trace)
+	if s.Windows > 0 && s.Trace == "" {
+		viper.Set("workload.length", s.Windows)
+	}
+	viper.Set("storm.csv", s.Output)
+
+	return errors.Join(util.ValidateConfig()...)
+}
+
+// ReadTrace returns the input-rate trace of the scenario, cut to its duration.
+func (s *Scenario) ReadTrace() ([]int64, error) {
+	trace, err := ReadTrace()
+	if err != nil {
+		return nil, err
+	}
+	if s.Windows > 0 && len(trace) > s.Windows {
+		trace = trace[:s.Windows]
+	}
+	return trace, nil
+}
+
+// Run simulates every model and preset of the scenario, repeated with the seeds seed, seed+1, ... of the workload
+// and the chaos layer. The stats of each run are saved in the output folder, the summary of the runs in its
+// summary.csv, and the statistics of the repetitions in its report.md.
+func (s *Scenario) Run() ([]Result, error) {
+	if err := s.Apply(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(s.Output, 0755); err != nil {
+		return nil, fmt.Errorf("scenario %s: %v", s.Name, err)
+	}
+
+	var results []Result
+	seed := viper.GetInt64("workload.seed")
+	for repetition := 0; repetition < s.Repetitions; repetition++ {
+		viper.Set("workload.seed", seed+int64(repetition))
+		viper.Set("chaos.seed", seed+int64(repetition))
+		trace, err := s.ReadTrace()
+		if err != nil {
+			return nil, fmt.Errorf("scenario %s: %v", s.Name, err)
+		}
+
+		for _, model := range s.Models {
+			for _, preset := range s.Presets {
+				viper.Set("storm.adaptive.predictive_model", model)
+				if err := ApplyPreset(preset); err != nil {
+					return nil, err
+				}
+				run := runName(s.Name, model, preset)
+				if s.Repetitions > 1 {
+					run += fmt.Sprintf("-s%d", seed+int64(repetition))
+				}
+				log.Printf("scenario: run={%s}\n", run)
+				summary, err := Simulate(run, trace)
+				if err != nil {
+					return nil, fmt.Errorf("scenario %s: %v", s.Name, err)
+				}
+				results = append(results, Result{Scenario: s.Name, Run: run, Model: model, Preset: preset, Seed: seed + int64(repetition), Summary: summary})
+			}
+		}
+	}
+
+	if b, err := csvutil.Marshal(results); err != nil {
+		return nil, err
+	} else {
+		if err := os.WriteFile(s.Output+"/summary.csv", b, 0644); err != nil {
+			return nil, fmt.Errorf("scenario %s: %v", s.Name, err)
+		}
+	}
+	if f, err := os.Create(s.Output + "/report.md"); err != nil {
+		return nil, fmt.Errorf("scenario %s: %v", s.Name, err)
+	} else {
+		defer f.Close()
+		if err := WriteReport(f, s, Aggregate(results)); err != nil {
+			return nil, fmt.Errorf("scenario %s: %v", s.Name, err)
+		}
+	}
+	return results, nil
+}
+
+func runName(name, model, preset string) string {
+	if preset == "" {
+		return name + "-" + model
+	}
+	return name + "-" + model + "-" + preset
+}
+
+// ApplyPreset sets the keys of the preset over the loaded config, "" keeps the loaded values.
+func ApplyPreset(name string) error {
+	if name == "" {
+		return nil
+	}
+	if preset, ok := util.Presets[name]; !ok {
+		return fmt.Errorf("unknown preset %q", name)
+	} else {
+		for key, value := range preset {
+			viper.Set(key, value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package experiment
+
+import (
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/adaptive"
+	"github.com/montanaflynn/stats"
+	"github.com/spf13/viper"
+	"math/rand"
+)
+
+// Ranges are the bounds of the adaptive parameters sampled by the sensitivity analysis, both included.
+type Ranges struct {
+	Headroom          [2]float64
+	ScaleDownCooldown [2]int
+	PlanningSamples   [2]int
+}
+
+// Sample is a combination of the adaptive parameters and the result of its run.
+type Sample struct {
+	Headroom          float64 `csv:"headroom"`
+	ScaleDownCooldown int     `csv:"scale_down_cooldown"`
+	PlanningSamples   int     `csv:"planning_samples"`
+	// Distance is the fraction of windows and bolts whose replicas differ from the run of the loaded config
+	Distance float64 `csv:"distance"`
+	adaptive.Summary
+}
+
+// Sensitivity runs the trace with the loaded config, and then with n combinations of the adaptive parameters
+// sampled uniformly in the ranges. It returns the samples and the Pearson correlation of each parameter with the
+// distance of the replicas to the ones of the loaded config.
+func Sensitivity(trace []int64, n int, seed int64, ranges Ranges) ([]Sample, map[string]float64, error) {
+	controller, err := simulate("sensitivity-baseline", trace)
+	if err != nil {
+		return nil, nil, err
+	}
+	baseline := replicasPerWindow(controller.GetDecisions(), len(trace))
+
+	rng := rand.New(rand.NewSource(seed))
+	var samples []Sample
+	for i := 0; i < n; i++ {
+		sample := Sample{
+			Headroom:          ranges.Headroom[0] + rng.Float64()*(ranges.Headroom[1]-ranges.Headroom[0]),
+			ScaleDownCooldown: ranges.ScaleDownCooldown[0] + rng.Intn(ranges.ScaleDownCooldown[1]-ranges.ScaleDownCooldown[0]+1),
+			PlanningSamples:   ranges.PlanningSamples[0] + rng.Intn(ranges.PlanningSamples[1]-ranges.PlanningSamples[0]+1),
+		}
+		viper.Set("storm.adaptive.headroom", sample.Headroom)
+		viper.Set("storm.adaptive.scale_down_cooldown", sample.ScaleDownCooldown)
+		viper.Set("storm.adaptive.planning_samples", sample.PlanningSamples)
+
+		controller, err := simulate(fmt.Sprintf("sensitivity-%d", i), trace)
+		if err != nil {
+			return nil, nil, err
+		}
+		sample.Summary = controller.GetSummary()
+		sample.Distance = distance(baseline, replicasPerWindow(controller.GetDecisions(), len(trace)))
+		samples = append(samples, sample)
+	}
+
+	var headroom, cooldown, planning, distances []float64
+	for _, sample := range samples {
+		headroom = append(headroom, sample.Headroom)
+		cooldown = append(cooldown, float64(sample.ScaleDownCooldown))
+		planning = append(planning, float64(sample.PlanningSamples))
+		distances = append(distances, sample.Distance)
+	}
+	correlation := make(map[string]float64)
+	correlation["headroom"], _ = stats.Correlation(headroom, distances)
+	correlation["scale_down_cooldown"], _ = stats.Correlation(cooldown, distances)
+	correlation["planning_samples"], _ = stats.Correlation(planning, distances)
+	return samples, correlation, nil
+}
+
+// replicasPerWindow expands the decisions of the planning to the replicas of each bolt in each window.
+func replicasPerWindow(decisions []adaptive.Decision, windows int) map[string][]int64 {
+	replicas := make(map[string][]int64)
+	for _, decision := range decisions {
+		if _, ok := replicas[decision.Bolt]; !ok {
+			replicas[decision.Bolt] = make([]int64, windows)
+		}
+		for t := decision.Period; t < windows; t++ {
+			replicas[decision.Bolt][t] = decision.Replicas
+		}
+	}
+	return replicas
+}
+
+func distance(a, b map[string][]int64) float64 {
+	var differ, total int
+	for bolt, replicas := range a {
+		for t := range replicas {
+			total++
+			if other, ok := b[bolt]; !ok || other[t] != replicas[t] {
+				differ++
+			}
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(differ) / float64(total)
+}
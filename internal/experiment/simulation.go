@@ -0,0 +1,67 @@
+package experiment
+
+import (
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/adaptive"
+	"github.com/dwladdimiroc/sps-storm/internal/simulator"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/dwladdimiroc/sps-storm/internal/workload"
+	"github.com/spf13/viper"
+	"time"
+)
+
+// Simulate runs the adaptive system against the simulated topology of the simulator section, fed with the given
+// trace, and returns the summary of the run. The run uses a logical clock starting at simulator.start, so the same
+// config and trace always give the same decisions.
+func Simulate(topologyId string, trace []int64) (adaptive.Summary, error) {
+	controller, err := simulate(topologyId, trace)
+	if err != nil {
+		return adaptive.Summary{}, err
+	}
+	return controller.GetSummary(), nil
+}
+
+// simulate is Simulate, returning the controller of the run once it is stopped.
+func simulate(topologyId string, trace []int64) (*adaptive.Controller, error) {
+	var bolts []simulator.BoltSpec
+	if err := util.UnmarshalSection("simulator.bolts", &bolts); err != nil {
+		return nil, fmt.Errorf("simulate: %v", err)
+	}
+
+	sim, err := simulator.New(topologyId, viper.GetString("simulator.spout"), bolts, trace, viper.GetFloat64("storm.adaptive.time_window_size"))
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := time.Parse(time.RFC3339, viper.GetString("simulator.start"))
+	if err != nil {
+		return nil, fmt.Errorf("simulate: %v", err)
+	}
+	util.SetClock(util.NewLogicalClock(start))
+	defer util.SetClock(util.RealClock{})
+
+	// The simulated latency comes from the simulator, not from the REST server
+	viper.Set("features.rest_api", false)
+
+	controller := adaptive.New(sim, topologyId)
+	controller.Run(sim.Windows())
+	controller.Stop()
+	return controller, nil
+}
+
+// ReadTrace reads simulator.trace, or generates the synthetic trace of the workload section when it is empty.
+func ReadTrace() ([]int64, error) {
+	if filename := viper.GetString("simulator.trace"); filename != "" {
+		return simulator.ReadTrace(filename)
+	}
+	return GenerateWorkload()
+}
+
+// GenerateWorkload generates the synthetic trace of the workload section.
+func GenerateWorkload() ([]int64, error) {
+	var spec workload.Spec
+	if err := util.UnmarshalSection("workload", &spec); err != nil {
+		return nil, fmt.Errorf("workload: %v", err)
+	}
+	return workload.Generate(spec)
+}
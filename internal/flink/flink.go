@@ -0,0 +1,298 @@
+package flink
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/spf13/viper"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type jobsOverview struct {
+	Jobs []struct {
+		Jid   string `json:"jid"`
+		Name  string `json:"name"`
+		State string `json:"state"`
+	} `json:"jobs"`
+}
+
+type job struct {
+	Jid      string   `json:"jid"`
+	Name     string   `json:"name"`
+	State    string   `json:"state"`
+	Vertices []vertex `json:"vertices"`
+}
+
+type vertex struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Parallelism int64  `json:"parallelism"`
+	Metrics     struct {
+		ReadRecords  int64 `json:"read-records"`
+		WriteRecords int64 `json:"write-records"`
+	} `json:"metrics"`
+}
+
+type plan struct {
+	Plan struct {
+		Nodes []struct {
+			Id     string `json:"id"`
+			Inputs []struct {
+				Id string `json:"id"`
+			} `json:"inputs"`
+		} `json:"nodes"`
+	} `json:"plan"`
+}
+
+type aggregatedMetric struct {
+	Id  string  `json:"id"`
+	Avg float64 `json:"avg"`
+}
+
+type resourceRequirements map[string]struct {
+	Parallelism struct {
+		LowerBound int64 `json:"lowerBound"`
+		UpperBound int64 `json:"upperBound"`
+	} `json:"parallelism"`
+}
+
+// Flink is the Cluster of a Flink job, managed through the REST API of the JobManager: the sources of the job are
+// the spouts and the rest of the operators (vertices, by name) are the bolts. The replicas of an operator are its
+// parallelism, changed with the resource requirements of the adaptive scheduler (reactive or adaptive mode), and
+// the latency comes from the REST server, as in Storm.
+type Flink struct {
+	url         string
+	jobId       string
+	vertices    map[string]vertex   // by name
+	order       []string            // names of the vertices, in the order of the job
+	inputs      map[string][]string // names of the inputs of each vertex, by name
+	readRecords map[string]int64    // read records of each vertex in the previous window
+	latency     map[string]float64  // execute latency (ms) of each vertex in the previous window
+}
+
+func New() *Flink {
+	return &Flink{
+		url:         fmt.Sprintf("http://%s:%s", viper.GetString("flink.host"), viper.GetString("flink.port")),
+		vertices:    make(map[string]vertex),
+		inputs:      make(map[string][]string),
+		readRecords: make(map[string]int64),
+		latency:     make(map[string]float64),
+	}
+}
+
+// GetTopologyId returns flink.job_id, or the first running job.
+func (f *Flink) GetTopologyId() string {
+	if jobId := viper.GetString("flink.job_id"); jobId != "" {
+		return jobId
+	}
+	var overview jobsOverview
+	if err := f.get("/jobs/overview", &overview); err != nil {
+		log.Printf("flink get jobs: %v\n", err)
+	}
+	for _, job := range overview.Jobs {
+		if job.State == "RUNNING" {
+			return job.Jid
+		}
+	}
+	time.Sleep(1 * time.Second)
+	return f.GetTopologyId()
+}
+
+func (f *Flink) GetSummaryTopology(topologyId string) storm.SummaryTopology {
+	summary := storm.SummaryTopology{Id: topologyId}
+	if err := f.update(topologyId); err != nil {
+		log.Printf("flink get summary topology: %v\n", err)
+		time.Sleep(1 * time.Second)
+		return f.GetSummaryTopology(topologyId)
+	}
+	summary.Name = topologyId
+	for _, name := range f.order {
+		if len(f.inputs[name]) == 0 {
+			summary.Spouts = append(summary.Spouts, storm.SummarySpout{SpoutId: name})
+		} else {
+			summary.Bolts = append(summary.Bolts, storm.SummaryBolt{BoltID: name})
+		}
+	}
+	return summary
+}
+
+func (f *Flink) GetComponentBolt(topologyId, boltName string) storm.BoltMetrics {
+	metrics := storm.BoltMetrics{Id: boltName}
+	for _, input := range f.inputs[boltName] {
+		metrics.InputStats = append(metrics.InputStats, storm.BoltInputStats{Component: input})
+	}
+	return metrics
+}
+
+func (f *Flink) GetMetrics(topology storm.Topology) (bool, storm.TopologyMetrics) {
+	var metrics storm.TopologyMetrics
+	if err := f.update(topology.Id); err != nil {
+		log.Printf("flink get metrics: %v\n", err)
+		return false, metrics
+	}
+
+	for _, spout := range topology.Spouts {
+		v := f.vertices[spout.Name]
+		spoutMetrics := storm.SpoutMetrics{Id: spout.Name}
+		spoutMetrics.SpoutSummary = append(spoutMetrics.SpoutSummary, storm.SpoutSummary{Emitted: int(v.Metrics.WriteRecords), Window: ":all-time"})
+		for _, successor := range f.successors(spout.Name) {
+			spoutMetrics.OutputStats = append(spoutMetrics.OutputStats, storm.SpoutOutputStats{Emitted: int(v.Metrics.WriteRecords), Stream: successor})
+		}
+		metrics.Spouts = append(metrics.Spouts, spoutMetrics)
+	}
+
+	for _, bolt := range topology.Bolts {
+		v := f.vertices[bolt.Name]
+		boltMetrics := f.GetComponentBolt(topology.Id, bolt.Name)
+		boltMetrics.BoltStats = append(boltMetrics.BoltStats, storm.BoltStats{
+			ExecuteLatency: strconv.FormatFloat(f.executeLatency(topology.Id, v), 'f', 3, 64),
+			Window:         ":all-time",
+			Executed:       v.Metrics.ReadRecords,
+		})
+		for _, successor := range f.successors(bolt.Name) {
+			boltMetrics.OutputStats = append(boltMetrics.OutputStats, storm.BoltOutputStats{Emitted: v.Metrics.WriteRecords, Stream: successor})
+		}
+		metrics.Bolts = append(metrics.Bolts, boltMetrics)
+	}
+	return true, metrics
+}
+
+func (f *Flink) GetLatency() float64 {
+	return util.GetLatency()
+}
+
+func (f *Flink) SetReplicas(bolt string, replicas int64) error {
+	v, ok := f.vertices[bolt]
+	if !ok {
+		return fmt.Errorf("flink: unknown operator %s", bolt)
+	}
+	var requirements resourceRequirements
+	if err := f.get("/jobs/"+f.jobId+"/resource-requirements", &requirements); err != nil {
+		return err
+	}
+	requirement := requirements[v.Id]
+	requirement.Parallelism.LowerBound = 1
+	requirement.Parallelism.UpperBound = replicas
+	requirements[v.Id] = requirement
+	return f.put("/jobs/"+f.jobId+"/resource-requirements", requirements)
+}
+
+// GetStatus maps the state of the job to the status of a topology: a job restarting, as when the adaptive
+// scheduler changes its parallelism, is rebalancing, and a job that is no longer running is killed.
+func (f *Flink) GetStatus(topologyId string) (string, error) {
+	var j job
+	if err := f.get("/jobs/"+topologyId, &j); err != nil {
+		return "", err
+	}
+	switch j.State {
+	case "RUNNING":
+		return storm.StatusActive, nil
+	case "CREATED", "INITIALIZING", "RESTARTING", "RECONCILING":
+		return storm.StatusRebalancing, nil
+	case "SUSPENDED":
+		return storm.StatusInactive, nil
+	default:
+		return storm.StatusKilled, nil
+	}
+}
+
+// update reads the vertices of the job and the inputs of each one.
+func (f *Flink) update(jobId string) error {
+	var j job
+	if err := f.get("/jobs/"+jobId, &j); err != nil {
+		return err
+	}
+	var p plan
+	if err := f.get("/jobs/"+jobId+"/plan", &p); err != nil {
+		return err
+	}
+
+	f.jobId = jobId
+	names := make(map[string]string)
+	f.order = nil
+	for _, v := range j.Vertices {
+		f.order = append(f.order, v.Name)
+		f.vertices[v.Name] = v
+		names[v.Id] = v.Name
+	}
+	for _, node := range p.Plan.Nodes {
+		var inputs []string
+		for _, input := range node.Inputs {
+			inputs = append(inputs, names[input.Id])
+		}
+		f.inputs[names[node.Id]] = inputs
+	}
+	return nil
+}
+
+func (f *Flink) successors(name string) []string {
+	var successors []string
+	for _, successor := range f.order {
+		for _, input := range f.inputs[successor] {
+			if input == name {
+				successors = append(successors, successor)
+			}
+		}
+	}
+	return successors
+}
+
+// executeLatency estimates the time (ms) that a subtask of the vertex spends per record: the busy time of its
+// subtasks over the records that each one reads per second in the window.
+func (f *Flink) executeLatency(jobId string, v vertex) float64 {
+	records := v.Metrics.ReadRecords - f.readRecords[v.Name]
+	f.readRecords[v.Name] = v.Metrics.ReadRecords
+	window := viper.GetFloat64("storm.adaptive.time_window_size")
+	if records <= 0 || v.Parallelism == 0 || window == 0 {
+		return f.latency[v.Name]
+	}
+
+	var busy []aggregatedMetric
+	if err := f.get("/jobs/"+jobId+"/vertices/"+v.Id+"/subtasks/metrics?get=busyTimeMsPerSecond&agg=avg", &busy); err != nil || len(busy) == 0 {
+		return f.latency[v.Name]
+	}
+	recordsPerSecond := float64(records) / window / float64(v.Parallelism)
+	f.latency[v.Name] = busy[0].Avg / recordsPerSecond
+	return f.latency[v.Name]
+}
+
+func (f *Flink) get(path string, out interface{}) error {
+	res, err := http.Get(f.url + path)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("flink get %s: %s", path, res.Status)
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (f *Flink) put(path string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, f.url+path, strings.NewReader(string(data)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("flink put %s: %s: %s", path, res.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package heron
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/spf13/viper"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type response struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Result  json.RawMessage `json:"result"`
+}
+
+type stream struct {
+	ComponentName string `json:"component_name"`
+	StreamName    string `json:"stream_name"`
+}
+
+type logicalPlan struct {
+	Spouts map[string]struct {
+		Outputs []stream `json:"outputs"`
+	} `json:"spouts"`
+	Bolts map[string]struct {
+		Inputs  []stream `json:"inputs"`
+		Outputs []stream `json:"outputs"`
+	} `json:"bolts"`
+}
+
+type componentMetrics struct {
+	Metrics map[string]map[string]interface{} `json:"metrics"` // metric -> instance -> value
+}
+
+// Heron is the Cluster of a Heron topology: the logical plan and the metrics come from the Heron Tracker, and the
+// parallelism of the components is changed with `heron update`. The latency comes from the REST server, as in
+// Storm, since Heron topologies are API-compatible with Storm.
+type Heron struct {
+	tracker    string
+	topologyId string
+	plan       logicalPlan
+}
+
+func New() *Heron {
+	return &Heron{tracker: fmt.Sprintf("http://%s:%s", viper.GetString("heron.tracker_host"), viper.GetString("heron.tracker_port"))}
+}
+
+// GetTopologyId returns heron.topology, or the first topology of the cluster and environ.
+func (h *Heron) GetTopologyId() string {
+	if topology := viper.GetString("heron.topology"); topology != "" {
+		return topology
+	}
+	var topologies map[string]map[string]map[string][]string // cluster -> role -> environ -> topologies
+	if err := h.get("/topologies", url.Values{"cluster": {viper.GetString("heron.cluster")}}, &topologies); err != nil {
+		log.Printf("heron get topologies: %v\n", err)
+	}
+	for _, roles := range topologies {
+		for _, environs := range roles {
+			if names := environs[viper.GetString("heron.environ")]; len(names) > 0 {
+				return names[0]
+			}
+		}
+	}
+	time.Sleep(1 * time.Second)
+	return h.GetTopologyId()
+}
+
+func (h *Heron) GetSummaryTopology(topologyId string) storm.SummaryTopology {
+	if err := h.get("/topologies/logicalplan", h.query(topologyId), &h.plan); err != nil || len(h.plan.Bolts) == 0 {
+		log.Printf("heron get logical plan: %v\n", err)
+		time.Sleep(1 * time.Second)
+		return h.GetSummaryTopology(topologyId)
+	}
+	h.topologyId = topologyId
+	summary := storm.SummaryTopology{Id: topologyId, Name: topologyId}
+	for name := range h.plan.Spouts {
+		summary.Spouts = append(summary.Spouts, storm.SummarySpout{SpoutId: name})
+	}
+	for name := range h.plan.Bolts {
+		summary.Bolts = append(summary.Bolts, storm.SummaryBolt{BoltID: name})
+	}
+	sort.Slice(summary.Spouts, func(i, j int) bool { return summary.Spouts[i].SpoutId < summary.Spouts[j].SpoutId })
+	sort.Slice(summary.Bolts, func(i, j int) bool { return summary.Bolts[i].BoltID < summary.Bolts[j].BoltID })
+	return summary
+}
+
+func (h *Heron) GetComponentBolt(topologyId, boltName string) storm.BoltMetrics {
+	metrics := storm.BoltMetrics{Id: boltName}
+	for _, input := range h.plan.Bolts[boltName].Inputs {
+		metrics.InputStats = append(metrics.InputStats, storm.BoltInputStats{Component: input.ComponentName})
+	}
+	return metrics
+}
+
+func (h *Heron) GetMetrics(topology storm.Topology) (bool, storm.TopologyMetrics) {
+	var metrics storm.TopologyMetrics
+	for _, spout := range topology.Spouts {
+		emitted, err := h.componentMetrics(topology.Id, spout.Name)
+		if err != nil {
+			log.Printf("heron get metrics: %v\n", err)
+			return false, metrics
+		}
+		spoutMetrics := storm.SpoutMetrics{Id: spout.Name}
+		var total int64
+		for _, value := range emitted["__emit-count"] {
+			total += int64(value)
+		}
+		spoutMetrics.SpoutSummary = append(spoutMetrics.SpoutSummary, storm.SpoutSummary{Emitted: int(total), Window: ":all-time"})
+		for successor, count := range h.emittedTo(spout.Name, emitted["__emit-count"]) {
+			spoutMetrics.OutputStats = append(spoutMetrics.OutputStats, storm.SpoutOutputStats{Emitted: int(count), Stream: successor})
+		}
+		metrics.Spouts = append(metrics.Spouts, spoutMetrics)
+	}
+
+	for _, bolt := range topology.Bolts {
+		values, err := h.componentMetrics(topology.Id, bolt.Name)
+		if err != nil {
+			log.Printf("heron get metrics: %v\n", err)
+			return false, metrics
+		}
+		boltMetrics := h.GetComponentBolt(topology.Id, bolt.Name)
+		var executed int64
+		var latency, latencies float64
+		for _, value := range values["__execute-count"] {
+			executed += int64(value)
+		}
+		for _, value := range values["__execute-latency"] {
+			latency += value
+			latencies++
+		}
+		if latencies > 0 {
+			latency /= latencies
+		}
+		boltMetrics.BoltStats = append(boltMetrics.BoltStats, storm.BoltStats{
+			ExecuteLatency: strconv.FormatFloat(latency/1e6, 'f', 3, 64), // ns to ms
+			Window:         ":all-time",
+			Executed:       executed,
+		})
+		for successor, count := range h.emittedTo(bolt.Name, values["__emit-count"]) {
+			boltMetrics.OutputStats = append(boltMetrics.OutputStats, storm.BoltOutputStats{Emitted: count, Stream: successor})
+		}
+		metrics.Bolts = append(metrics.Bolts, boltMetrics)
+	}
+	return true, metrics
+}
+
+func (h *Heron) GetLatency() float64 {
+	return util.GetLatency()
+}
+
+// GetStatus reports the topology as active, since the tracker does not report an update in progress.
+func (h *Heron) GetStatus(topologyId string) (string, error) {
+	return storm.StatusActive, nil
+}
+
+// SetReplicas changes the parallelism of the component with `heron update`.
+func (h *Heron) SetReplicas(bolt string, replicas int64) error {
+	return h.SetReplicasBatch(map[string]int64{bolt: replicas})
+}
+
+// SetReplicasBatch changes the parallelism of the components with a single `heron update`, since each update
+// restarts the topology. A failed update, with the output of the CLI, is returned, so the adaptive system counts
+// it.
+func (h *Heron) SetReplicasBatch(replicas map[string]int64) error {
+	location := viper.GetString("heron.cluster")
+	if role := viper.GetString("heron.role"); role != "" {
+		location += "/" + role + "/" + viper.GetString("heron.environ")
+	}
+	bolts := make([]string, 0, len(replicas))
+	for bolt := range replicas {
+		bolts = append(bolts, bolt)
+	}
+	sort.Strings(bolts)
+	args := []string{"update", location, h.topologyId}
+	for _, bolt := range bolts {
+		args = append(args, "--component-parallelism="+bolt+":"+strconv.FormatInt(replicas[bolt], 10))
+	}
+	log.Printf("heron: %s %v\n", viper.GetString("heron.cli"), args)
+	if output, err := exec.Command(viper.GetString("heron.cli"), args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("heron update: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// averaged are the metrics that componentMetrics averages over the instances of a component instead of adding
+// them, since they are not counters
+var averaged = map[string]bool{"__execute-latency": true}
+
+// componentMetrics returns the all-time metrics of the component, by metric and by stream (the part of the name
+// after the first /): the counters added over the instances, and the averaged metrics their mean.
+func (h *Heron) componentMetrics(topologyId, component string) (map[string]map[string]float64, error) {
+	query := h.query(topologyId)
+	query.Set("component", component)
+	query.Set("interval", "-1")
+	query["metricname"] = []string{"__emit-count", "__execute-count", "__execute-latency"}
+
+	var result componentMetrics
+	if err := h.get("/topologies/metrics", query, &result); err != nil {
+		return nil, err
+	}
+	metrics := make(map[string]map[string]float64)
+	for name, instances := range result.Metrics {
+		metric, stream, _ := strings.Cut(name, "/")
+		if metrics[metric] == nil {
+			metrics[metric] = make(map[string]float64)
+		}
+		for _, value := range instances {
+			metrics[metric][stream] += number(value)
+		}
+		if averaged[metric] && len(instances) > 0 {
+			metrics[metric][stream] /= float64(len(instances))
+		}
+	}
+	return metrics, nil
+}
+
+// emittedTo returns the tuples emitted by the component to each bolt, from the emit count of each stream and the
+// streams subscribed by the bolts.
+func (h *Heron) emittedTo(component string, streams map[string]float64) map[string]int64 {
+	emitted := make(map[string]int64)
+	for name, bolt := range h.plan.Bolts {
+		for _, input := range bolt.Inputs {
+			if input.ComponentName == component {
+				emitted[name] += int64(streams[input.StreamName])
+			}
+		}
+	}
+	return emitted
+}
+
+func (h *Heron) query(topologyId string) url.Values {
+	return url.Values{
+		"cluster":  {viper.GetString("heron.cluster")},
+		"environ":  {viper.GetString("heron.environ")},
+		"topology": {topologyId},
+	}
+}
+
+func (h *Heron) get(path string, query url.Values, out interface{}) error {
+	res, err := http.Get(h.tracker + path + "?" + query.Encode())
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	var r response
+	if err := json.Unmarshal(data, &r); err != nil {
+		return err
+	}
+	if r.Status != "" && r.Status != "success" {
+		return fmt.Errorf("heron get %s: %s", path, r.Message)
+	}
+	return json.Unmarshal(r.Result, out)
+}
+
+// number parses the value of a metric, a number or a string depending on the version of the tracker.
+func number(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case string:
+		n, _ := strconv.ParseFloat(v, 64)
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,70 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/viper"
+	"time"
+)
+
+var client *kafka.Client
+var startOffsets int64
+var started bool
+
+// Produced returns the messages produced in the source topics of the topology (kafka.topics) since the first
+// call: the growth of the end offsets of their partitions. The growth is measured at the broker, so it is the
+// load offered to the topology even when the spouts are slowed down by backpressure.
+func Produced() (int64, error) {
+	if client == nil {
+		client = &kafka.Client{
+			Addr:    kafka.TCP(viper.GetStringSlice("kafka.brokers")...),
+			Timeout: time.Duration(viper.GetInt("kafka.timeout")) * time.Millisecond,
+		}
+	}
+	offsets, err := endOffsets()
+	if err != nil {
+		return 0, err
+	}
+	if !started {
+		startOffsets = offsets
+		started = true
+	}
+	return offsets - startOffsets, nil
+}
+
+// endOffsets returns the sum of the end offsets of every partition of the source topics.
+func endOffsets() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(viper.GetInt("kafka.timeout"))*time.Millisecond)
+	defer cancel()
+
+	topics := viper.GetStringSlice("kafka.topics")
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: topics})
+	if err != nil {
+		return 0, err
+	}
+	requests := make(map[string][]kafka.OffsetRequest)
+	for _, topic := range metadata.Topics {
+		if topic.Error != nil {
+			return 0, fmt.Errorf("kafka topic %s: %v", topic.Name, topic.Error)
+		}
+		for _, partition := range topic.Partitions {
+			requests[topic.Name] = append(requests[topic.Name], kafka.LastOffsetOf(partition.ID))
+		}
+	}
+
+	response, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: requests})
+	if err != nil {
+		return 0, err
+	}
+	var offsets int64
+	for topic, partitions := range response.Topics {
+		for _, partition := range partitions {
+			if partition.Error != nil {
+				return 0, fmt.Errorf("kafka topic %s partition %d: %v", topic, partition.Partition, partition.Error)
+			}
+			offsets += partition.LastOffset
+		}
+	}
+	return offsets, nil
+}
@@ -0,0 +1,96 @@
+package kubernetes
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const serviceAccount = "/var/run/secrets/kubernetes.io/serviceaccount/"
+
+// Client is a minimal client of the Kubernetes API. Inside a pod it uses the service account of the pod, and
+// outside kubernetes.api_server and kubernetes.token_file.
+type Client struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+func NewClient() (*Client, error) {
+	server := viper.GetString("kubernetes.api_server")
+	tokenFile := viper.GetString("kubernetes.token_file")
+	tlsConfig := &tls.Config{}
+	if server == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" {
+			return nil, fmt.Errorf("kubernetes: not in a cluster and kubernetes.api_server is empty")
+		}
+		server = "https://" + host + ":" + port
+		if tokenFile == "" {
+			tokenFile = serviceAccount + "token"
+		}
+		if ca, err := os.ReadFile(serviceAccount + "ca.crt"); err != nil {
+			return nil, fmt.Errorf("kubernetes: %v", err)
+		} else {
+			tlsConfig.RootCAs = x509.NewCertPool()
+			tlsConfig.RootCAs.AppendCertsFromPEM(ca)
+		}
+	} else if viper.GetBool("kubernetes.insecure") {
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	client := &Client{server: strings.TrimSuffix(server, "/"), http: &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}}
+	if tokenFile != "" {
+		if token, err := os.ReadFile(tokenFile); err != nil {
+			return nil, fmt.Errorf("kubernetes: %v", err)
+		} else {
+			client.token = strings.TrimSpace(string(token))
+		}
+	}
+	return client, nil
+}
+
+// Do sends a request to the API with a JSON body (nil for none) and decodes the JSON response in out (nil to
+// discard it).
+func (c *Client) Do(method, path, contentType string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		if b, err := json.Marshal(body); err != nil {
+			return err
+		} else {
+			reader = bytes.NewReader(b)
+		}
+	}
+	req, err := http.NewRequest(method, c.server+path, reader)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("kubernetes %s %s: %s: %s", method, path, res.Status, strings.TrimSpace(string(data)))
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"github.com/dwladdimiroc/sps-storm/internal/adaptive"
+	"github.com/spf13/viper"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The external metrics of the adaptive system, served with the external metrics API of Kubernetes, so that a
+// HorizontalPodAutoscaler scales the worker pods from the same forecasts as the bolts:
+//   - sps_predicted_input_rate: input rate predicted for the current window (tuples per window).
+//   - sps_bolt_pressure: predicted replicas over current replicas of each bolt (label bolt), above 1 when the
+//     bolt needs more replicas than it has.
+//   - sps_sla_score: compliance of the last window with the SLA of the topology, from 0 to 1.
+const externalMetricsGroupVersion = "external.metrics.k8s.io/v1beta1"
+
+type externalMetricValue struct {
+	MetricName   string            `json:"metricName"`
+	MetricLabels map[string]string `json:"metricLabels"`
+	Timestamp    string            `json:"timestamp"`
+	Value        string            `json:"value"`
+}
+
+type externalMetricValueList struct {
+	Kind       string                `json:"kind"`
+	ApiVersion string                `json:"apiVersion"`
+	Metadata   struct{}              `json:"metadata"`
+	Items      []externalMetricValue `json:"items"`
+}
+
+type apiResource struct {
+	Name       string   `json:"name"`
+	Namespaced bool     `json:"namespaced"`
+	Kind       string   `json:"kind"`
+	Verbs      []string `json:"verbs"`
+}
+
+type apiResourceList struct {
+	Kind         string        `json:"kind"`
+	ApiVersion   string        `json:"apiVersion"`
+	GroupVersion string        `json:"groupVersion"`
+	Resources    []apiResource `json:"resources"`
+}
+
+// ServeExternalMetrics serves the external metrics API, with TLS when kubernetes.tls_cert and kubernetes.tls_key
+// are set, since the aggregation layer of Kubernetes only talks TLS. The metrics are the snapshot of the controller.
+func ServeExternalMetrics(controller *adaptive.Controller) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apis/"+externalMetricsGroupVersion, discovery)
+	mux.HandleFunc("/apis/"+externalMetricsGroupVersion+"/namespaces/", metrics{controller}.externalMetrics)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	addr := ":" + viper.GetString("kubernetes.metrics_port")
+	log.Printf("external metrics: init,addr={%s}\n", addr)
+	var err error
+	if cert, key := viper.GetString("kubernetes.tls_cert"), viper.GetString("kubernetes.tls_key"); cert != "" && key != "" {
+		err = http.ListenAndServeTLS(addr, cert, key, mux)
+	} else {
+		err = http.ListenAndServe(addr, mux)
+	}
+	log.Printf("external metrics: error={%v}\n", err)
+}
+
+func discovery(w http.ResponseWriter, r *http.Request) {
+	verbs := []string{"get"}
+	writeJson(w, apiResourceList{
+		Kind:         "APIResourceList",
+		ApiVersion:   "v1",
+		GroupVersion: externalMetricsGroupVersion,
+		Resources: []apiResource{
+			{Name: "sps_predicted_input_rate", Namespaced: true, Kind: "ExternalMetricValueList", Verbs: verbs},
+			{Name: "sps_bolt_pressure", Namespaced: true, Kind: "ExternalMetricValueList", Verbs: verbs},
+			{Name: "sps_sla_score", Namespaced: true, Kind: "ExternalMetricValueList", Verbs: verbs},
+		},
+	})
+}
+
+// metrics serves the external metrics of a controller.
+type metrics struct {
+	controller *adaptive.Controller
+}
+
+// externalMetrics serves /apis/external.metrics.k8s.io/v1beta1/namespaces/<namespace>/<metric>. The metrics do
+// not depend on the namespace, and the labelSelector supports equalities (bolt=count).
+func (m metrics) externalMetrics(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, "/apis/"+externalMetricsGroupVersion+"/namespaces/"), "/")
+	if len(path) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	selector := parseSelector(r.URL.Query().Get("labelSelector"))
+
+	topology := m.controller.GetSnapshot()
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	list := externalMetricValueList{Kind: "ExternalMetricValueList", ApiVersion: externalMetricsGroupVersion, Items: []externalMetricValue{}}
+	switch path[1] {
+	case "sps_predicted_input_rate":
+		list.Items = append(list.Items, externalMetricValue{MetricName: path[1], MetricLabels: map[string]string{"topology": topology.Id},
+			Timestamp: timestamp, Value: strconv.FormatInt(topology.PredictedInputRateT, 10)})
+	case "sps_bolt_pressure":
+		for _, bolt := range topology.Bolts {
+			labels := map[string]string{"topology": topology.Id, "bolt": bolt.Name}
+			if !matches(selector, labels) || bolt.Replicas == 0 {
+				continue
+			}
+			pressure := float64(bolt.PredictionReplicas) / float64(bolt.Replicas)
+			list.Items = append(list.Items, externalMetricValue{MetricName: path[1], MetricLabels: labels,
+				Timestamp: timestamp, Value: strconv.FormatInt(int64(pressure*1000), 10) + "m"})
+		}
+	case "sps_sla_score":
+		list.Items = append(list.Items, externalMetricValue{MetricName: path[1], MetricLabels: map[string]string{"topology": topology.Id},
+			Timestamp: timestamp, Value: strconv.FormatInt(int64(topology.SlaScore*1000), 10) + "m"})
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	writeJson(w, list)
+}
+
+func parseSelector(selector string) map[string]string {
+	labels := make(map[string]string)
+	for _, requirement := range strings.Split(selector, ",") {
+		if key, value, ok := strings.Cut(requirement, "="); ok {
+			labels[strings.TrimSpace(key)] = strings.TrimSpace(strings.TrimPrefix(value, "="))
+		}
+	}
+	return labels
+}
+
+func matches(selector, labels map[string]string) bool {
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func writeJson(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("external metrics: error={%v}\n", err)
+	}
+}
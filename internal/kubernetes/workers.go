@@ -0,0 +1,107 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/spf13/viper"
+	"log"
+	"math"
+	"net/http"
+	"sync"
+)
+
+type scale struct {
+	Spec struct {
+		Replicas int64 `json:"replicas"`
+	} `json:"spec"`
+}
+
+// Workers wraps a cluster and scales the Deployment or StatefulSet of the Storm workers with the replicas of the
+// bolts, so that every executor has a slot: the workers are the replicas of all the bolts over
+// kubernetes.workers.executors_per_worker. A scale up adds the workers before the replicas of the bolt, and a
+// scale down removes them after, so the executors never wait for a slot.
+type Workers struct {
+	storm.Cluster
+	client   *Client
+	path     string
+	replicas map[string]int64
+	workers  int64
+	mu       sync.Mutex
+}
+
+func NewWorkers(c storm.Cluster) (*Workers, error) {
+	client, err := NewClient()
+	if err != nil {
+		return nil, err
+	}
+	workers := &Workers{
+		Cluster:  c,
+		client:   client,
+		path:     fmt.Sprintf("/apis/apps/v1/namespaces/%s/%s/%s/scale", viper.GetString("kubernetes.workers.namespace"), viper.GetString("kubernetes.workers.kind"), viper.GetString("kubernetes.workers.name")),
+		replicas: make(map[string]int64),
+	}
+
+	var current scale
+	if err := client.Do(http.MethodGet, workers.path, "", nil, &current); err != nil {
+		return nil, err
+	}
+	workers.workers = current.Spec.Replicas
+	log.Printf("kubernetes: workers={%s},replicas={%d}\n", workers.path, workers.workers)
+	return workers, nil
+}
+
+func (w *Workers) SetReplicas(bolt string, replicas int64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	previous, ok := w.replicas[bolt]
+	w.replicas[bolt] = replicas
+	workers := w.requiredWorkers()
+	if workers > w.workers {
+		if err := w.scale(workers); err != nil {
+			w.restore(bolt, previous, ok)
+			return err
+		}
+	}
+	if err := w.Cluster.SetReplicas(bolt, replicas); err != nil {
+		w.restore(bolt, previous, ok)
+		return err
+	}
+	if workers < w.workers {
+		return w.scale(workers)
+	}
+	return nil
+}
+
+func (w *Workers) restore(bolt string, previous int64, ok bool) {
+	if ok {
+		w.replicas[bolt] = previous
+	} else {
+		delete(w.replicas, bolt)
+	}
+}
+
+func (w *Workers) requiredWorkers() int64 {
+	var executors int64
+	for _, replicas := range w.replicas {
+		executors += replicas
+	}
+	workers := int64(math.Ceil(float64(executors) / viper.GetFloat64("kubernetes.workers.executors_per_worker")))
+	if min := viper.GetInt64("kubernetes.workers.min_workers"); workers < min {
+		return min
+	} else if max := viper.GetInt64("kubernetes.workers.max_workers"); workers > max {
+		return max
+	}
+	return workers
+}
+
+func (w *Workers) scale(workers int64) error {
+	patch := map[string]map[string]int64{"spec": {"replicas": workers}}
+	if err := w.client.Do(http.MethodPatch, w.path, "application/merge-patch+json", patch, nil); err != nil {
+		log.Printf("kubernetes: scale workers error={%v}\n", err)
+		return err
+	}
+	log.Printf("kubernetes: scale workers={%d},previous={%d}\n", workers, w.workers)
+	w.workers = workers
+	return nil
+}
@@ -0,0 +1,111 @@
+package mqtt
+
+import (
+	"fmt"
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/spf13/viper"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var client paho.Client
+
+// counter is the cumulative count of messages: the last value of mqtt.sys_topic, or the messages received in
+// mqtt.topics since the subscription.
+var counter int64
+var counted bool
+var counterLock sync.Mutex
+
+var startCounter int64
+var started bool
+
+// Received returns the messages received by the broker since the first call, for the mqtt input rate source.
+// With mqtt.counter sys, the count is the counter that the broker publishes in mqtt.sys_topic (all the
+// messages published to the broker); with topics, it is the messages published in mqtt.topics, counted by a
+// subscription of the adaptive system.
+func Received() (int64, error) {
+	if client == nil {
+		if err := connect(); err != nil {
+			return 0, err
+		}
+	}
+	counterLock.Lock()
+	defer counterLock.Unlock()
+	if !counted {
+		return 0, fmt.Errorf("mqtt: no message received yet")
+	}
+	if !started {
+		startCounter = counter
+		started = true
+	}
+	return counter - startCounter, nil
+}
+
+func connect() error {
+	options := paho.NewClientOptions().
+		AddBroker(viper.GetString("mqtt.broker")).
+		SetClientID(viper.GetString("mqtt.client_id")).
+		SetUsername(viper.GetString("mqtt.username")).
+		SetPassword(viper.GetString("mqtt.password")).
+		SetConnectTimeout(timeout()).
+		SetAutoReconnect(true)
+
+	c := paho.NewClient(options)
+	if err := wait(c.Connect()); err != nil {
+		return fmt.Errorf("mqtt connect %s: %v", viper.GetString("mqtt.broker"), err)
+	}
+
+	if viper.GetString("mqtt.counter") == "sys" {
+		if err := wait(c.Subscribe(viper.GetString("mqtt.sys_topic"), 0, sysCounter)); err != nil {
+			c.Disconnect(0)
+			return fmt.Errorf("mqtt subscribe %s: %v", viper.GetString("mqtt.sys_topic"), err)
+		}
+	} else {
+		filters := make(map[string]byte)
+		for _, topic := range viper.GetStringSlice("mqtt.topics") {
+			filters[topic] = 0
+		}
+		if len(filters) == 0 {
+			c.Disconnect(0)
+			return fmt.Errorf("mqtt: no topics in mqtt.topics")
+		}
+		if err := wait(c.SubscribeMultiple(filters, topicCounter)); err != nil {
+			c.Disconnect(0)
+			return fmt.Errorf("mqtt subscribe %v: %v", viper.GetStringSlice("mqtt.topics"), err)
+		}
+		// The subscription counts from zero, so the first call already has a counter
+		counterLock.Lock()
+		counted = true
+		counterLock.Unlock()
+	}
+	client = c
+	return nil
+}
+
+func sysCounter(_ paho.Client, msg paho.Message) {
+	if value, err := strconv.ParseInt(strings.TrimSpace(string(msg.Payload())), 10, 64); err == nil {
+		counterLock.Lock()
+		defer counterLock.Unlock()
+		counter = value
+		counted = true
+	}
+}
+
+func topicCounter(_ paho.Client, _ paho.Message) {
+	counterLock.Lock()
+	defer counterLock.Unlock()
+	counter++
+}
+
+func wait(token paho.Token) error {
+	if !token.WaitTimeout(timeout()) {
+		return fmt.Errorf("timeout")
+	}
+	return token.Error()
+}
+
+func timeout() time.Duration {
+	return time.Duration(viper.GetInt("mqtt.timeout")) * time.Millisecond
+}
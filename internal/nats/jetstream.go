@@ -0,0 +1,98 @@
+package nats
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type jsz struct {
+	AccountDetails []struct {
+		Name         string         `json:"name"`
+		StreamDetail []streamDetail `json:"stream_detail"`
+	} `json:"account_details"`
+}
+
+type streamDetail struct {
+	Name  string `json:"name"`
+	State struct {
+		Messages uint64 `json:"messages"`
+		LastSeq  uint64 `json:"last_seq"`
+	} `json:"state"`
+	ConsumerDetail []struct {
+		Name          string `json:"name"`
+		NumPending    uint64 `json:"num_pending"`
+		NumAckPending int64  `json:"num_ack_pending"`
+	} `json:"consumer_detail"`
+}
+
+var startSeq int64
+var started bool
+
+// Stored returns the messages stored in nats.stream since the first call: the growth of the last sequence of
+// the stream, which counts every message published even when the retention of the stream removes them.
+func Stored() (int64, error) {
+	stream, err := getStream()
+	if err != nil {
+		return 0, err
+	}
+	if !started {
+		startSeq = int64(stream.State.LastSeq)
+		started = true
+	}
+	return int64(stream.State.LastSeq) - startSeq, nil
+}
+
+// Lag returns the consumer lag of nats.consumer in nats.stream: the messages not yet delivered to the spouts
+// plus the messages delivered but not acknowledged.
+func Lag() (int64, error) {
+	stream, err := getStream()
+	if err != nil {
+		return 0, err
+	}
+	for _, consumer := range stream.ConsumerDetail {
+		if consumer.Name == viper.GetString("nats.consumer") {
+			return int64(consumer.NumPending) + consumer.NumAckPending, nil
+		}
+	}
+	return 0, fmt.Errorf("nats: consumer %s not found in stream %s", viper.GetString("nats.consumer"), stream.Name)
+}
+
+// getStream reads nats.stream from the JetStream endpoint of the monitoring server of NATS (/jsz), in the
+// account nats.account, or in any account when it is empty.
+func getStream() (streamDetail, error) {
+	client := http.Client{Timeout: time.Duration(viper.GetInt("nats.timeout")) * time.Millisecond}
+	url := strings.TrimSuffix(viper.GetString("nats.monitor_url"), "/") + "/jsz?accounts=true&streams=true&consumers=true"
+	if account := viper.GetString("nats.account"); account != "" {
+		url += "&acc=" + account
+	}
+	res, err := client.Get(url)
+	if err != nil {
+		return streamDetail{}, err
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return streamDetail{}, fmt.Errorf("nats get /jsz: %s", res.Status)
+	}
+	var info jsz
+	if err := json.Unmarshal(data, &info); err != nil {
+		return streamDetail{}, err
+	}
+
+	for _, account := range info.AccountDetails {
+		if viper.GetString("nats.account") != "" && account.Name != viper.GetString("nats.account") {
+			continue
+		}
+		for _, stream := range account.StreamDetail {
+			if stream.Name == viper.GetString("nats.stream") {
+				return stream, nil
+			}
+		}
+	}
+	return streamDetail{}, fmt.Errorf("nats: stream %s not found", viper.GetString("nats.stream"))
+}
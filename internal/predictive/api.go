@@ -2,17 +2,21 @@ package predictive
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"github.com/spf13/viper"
 	"io"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 )
 
 type Response struct {
 	AvgPrediction float64   `json:"avg_prediction"`
 	Predictions   []float64 `json:"predictions"`
+	TrainedAt     time.Time `json:"trained_at"`
+	Version       string    `json:"version"`
 }
 
 type PredictorData struct {
@@ -35,7 +39,13 @@ func parseURL(urlRaw string, predictorModel string) string {
 	return url
 }
 
+// Deprecated: use API.Forecast.
 func GetPrediction(samples []float64, predictionNumber int, predictorModel string) []float64 {
+	return defaultAPI.getPrediction(context.Background(), samples, predictionNumber, predictorModel)
+}
+
+// getPrediction requests the prediction of the model, with a context that cancels the request.
+func (a API) getPrediction(ctx context.Context, samples []float64, predictionNumber int, predictorModel string) []float64 {
 	var resp Response
 
 	var body = PredictorData{
@@ -46,8 +56,10 @@ func GetPrediction(samples []float64, predictionNumber int, predictorModel strin
 	if b, err := json.Marshal(body); err != nil {
 		log.Printf("storm get prediction: %v\n", err)
 	} else {
-		predictor := parseURL(PredictorURL, predictorModel)
-		if res, err := http.Post(predictor, "application/json", bytes.NewBuffer(b)); err != nil {
+		predictor := a.catalog.URL(predictorModel)
+		if req, err := http.NewRequestWithContext(ctx, http.MethodPost, predictor, bytes.NewBuffer(b)); err != nil {
+			log.Printf("storm get prediction: %v\n", err)
+		} else if res, err := http.DefaultClient.Do(req); err != nil {
 			log.Printf("storm get prediction: %v\n", err)
 		} else {
 			data, _ := io.ReadAll(res.Body)
@@ -56,6 +68,8 @@ func GetPrediction(samples []float64, predictionNumber int, predictorModel strin
 			} else {
 				if err := json.Unmarshal(data, &resp); err != nil {
 					log.Printf("storm get prediction: %v\n", err)
+				} else {
+					a.trainings.update(predictorModel, resp)
 				}
 			}
 		}
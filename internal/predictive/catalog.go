@@ -0,0 +1,82 @@
+package predictive
+
+import (
+	"log"
+	"sync"
+
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// Catalog are the models of predictor.catalog by name. The models out of the catalog are routes of the Predictor
+// API of predictor.host and predictor.port.
+type Catalog struct {
+	lock   sync.RWMutex
+	models map[string]util.CatalogModel
+	// watched are the catalog files watched, each one is read again when it changes
+	watched map[string]bool
+}
+
+// NewCatalog returns an empty Catalog, loaded by Load.
+func NewCatalog() *Catalog {
+	return &Catalog{watched: make(map[string]bool)}
+}
+
+// Load reads the catalog of predictor.catalog again, and watches the file so that it is read again when it
+// changes. When it cannot be read, the previous catalog is kept.
+func (c *Catalog) Load() {
+	c.watch(viper.GetString("predictor.catalog"))
+	models, err := util.ReadCatalog()
+	if err != nil {
+		log.Printf("predictor: catalog error={%v}\n", err)
+		return
+	}
+	loaded := make(map[string]util.CatalogModel, len(models))
+	for _, model := range models {
+		loaded[model.Name] = model
+		log.Printf("predictor: catalog model={%s},endpoint={%s},horizon={%d},resources={%v}\n", model.Name, model.Endpoint, model.Horizon, model.Resources)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.models = loaded
+}
+
+func (c *Catalog) watch(filename string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if filename == "" || c.watched[filename] {
+		return
+	}
+	c.watched[filename] = true
+	file := viper.New()
+	file.SetConfigFile(filename)
+	file.OnConfigChange(func(fsnotify.Event) {
+		// a file no longer in predictor.catalog is not read again
+		if viper.GetString("predictor.catalog") == filename {
+			log.Printf("predictor: catalog changed file={%s}\n", filename)
+			c.Load()
+		}
+	})
+	file.WatchConfig()
+}
+
+// URL returns the endpoint of the model in the catalog, or its route of the Predictor API.
+func (c *Catalog) URL(model string) string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if m, ok := c.models[model]; ok {
+		return m.Endpoint
+	}
+	return parseURL(PredictorURL, model)
+}
+
+// defaultCatalog is the Catalog of the API behind the functions of the package
+var defaultCatalog = NewCatalog()
+
+// LoadCatalog loads the Catalog behind the functions of the package.
+//
+// Deprecated: use Catalog.Load.
+func LoadCatalog() {
+	defaultCatalog.Load()
+}
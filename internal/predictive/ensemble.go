@@ -0,0 +1,112 @@
+package predictive
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Ensemble is the Forecaster of several models of the Predictor API. The models are queried by Workers
+// concurrent requests, and all of them must answer within Timeout; the forecast is the mean, period by period,
+// of the models that answered in time.
+type Ensemble struct {
+	API
+	Models  []string
+	Workers int
+	Timeout time.Duration
+}
+
+// EnsembleFromViper returns the Ensemble of storm.adaptive.ensemble, predictor.workers and predictor.timeout, whose
+// models are queried through the API.
+func EnsembleFromViper(api API) Ensemble {
+	return Ensemble{
+		API:     api,
+		Models:  viper.GetStringSlice("storm.adaptive.ensemble"),
+		Workers: viper.GetInt("predictor.workers"),
+		Timeout: time.Duration(viper.GetInt("predictor.timeout")) * time.Millisecond,
+	}
+}
+
+// ForecasterFromViper returns the Ensemble when storm.adaptive.ensemble has models, and API otherwise, with the
+// catalog of predictor.catalog loaded in the given Catalog.
+func ForecasterFromViper(catalog *Catalog) Forecaster {
+	catalog.Load()
+	api := NewAPI(catalog)
+	if len(viper.GetStringSlice("storm.adaptive.ensemble")) > 0 {
+		return EnsembleFromViper(api)
+	}
+	return api
+}
+
+// Forecast queries every model of the ensemble; the model given by the Predictor is not used.
+func (e Ensemble) Forecast(samples []float64, predictionNumber int, _ string) []float64 {
+	ctx, cancel := context.WithTimeout(context.Background(), e.Timeout)
+	defer cancel()
+
+	results := make([][]float64, len(e.Models))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	workers := e.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = e.getPrediction(ctx, samples, predictionNumber, e.Models[i])
+			}
+		}()
+	}
+	for i := range e.Models {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	excluded := e.staleModels(results)
+	var sum []float64
+	var count []int
+	for i, result := range results {
+		if len(result) == 0 {
+			log.Printf("predictor ensemble: model={%s} without prediction\n", e.Models[i])
+			continue
+		}
+		if excluded[i] {
+			continue
+		}
+		for j, value := range result {
+			if j == len(sum) {
+				sum = append(sum, 0)
+				count = append(count, 0)
+			}
+			sum[j] += value
+			count[j]++
+		}
+	}
+	for j := range sum {
+		sum[j] /= float64(count[j])
+	}
+	return sum
+}
+
+// staleModels returns the models that answered but are stale, with predictor.exclude_stale, so that the forecast is
+// the mean of the fresh ones. When every model that answered is stale, none is excluded.
+func (e Ensemble) staleModels(results [][]float64) []bool {
+	excluded := make([]bool, len(e.Models))
+	fresh := false
+	for i, result := range results {
+		if len(result) > 0 {
+			excluded[i] = e.trainings.stale(e.Models[i])
+			fresh = fresh || !excluded[i]
+		}
+	}
+	if !fresh || !viper.GetBool("predictor.exclude_stale") {
+		return make([]bool, len(e.Models))
+	}
+	return excluded
+}
@@ -1,56 +1,158 @@
 package predictive
 
 import (
+	"context"
+
 	"github.com/dwladdimiroc/sps-storm/internal/storm"
 	"github.com/spf13/viper"
 )
 
-var predictions PredictionInput
-
 type PredictionInput struct {
 	NameModel      string
 	PredictedInput []float64
 }
 
-func GetPred() PredictionInput {
-	return predictions
+// Forecaster forecasts the next predictionNumber input rates from the samples with the given model.
+type Forecaster interface {
+	Forecast(samples []float64, predictionNumber int, predictorModel string) []float64
 }
 
-func InitPrediction() {
-	predictions.NameModel = viper.GetString("storm.adaptive.predictive_model")
-	predictions.PredictedInput = make([]float64, viper.GetInt("storm.adaptive.analyze_samples"))
+// API is the Forecaster of the Predictor API (predictor.host and predictor.port) and of the models of its
+// Catalog. It keeps the training reported by each model, to flag the stale ones.
+type API struct {
+	catalog   *Catalog
+	trainings *trainings
 }
 
-func PredictInput(topology *storm.Topology) {
-	var samples []float64
+// NewAPI returns the API of the models of the catalog.
+func NewAPI(catalog *Catalog) API {
+	return API{catalog: catalog, trainings: newTrainings()}
+}
+
+func (a API) Forecast(samples []float64, predictionNumber int, predictorModel string) []float64 {
+	predictions := a.getPrediction(context.Background(), samples, predictionNumber, predictorModel)
+	a.trainings.stale(predictorModel)
+	return predictions
+}
 
-	var index int
-	if index = len(topology.InputRate) - viper.GetInt("storm.adaptive.prediction_samples"); index < 0 {
-		index = 0
+// defaultAPI is the API behind the functions of the package
+var defaultAPI = NewAPI(defaultCatalog)
+
+// Config holds the parameters of a Predictor, so that a Predictor does not depend on the global config.
+type Config struct {
+	Model             string
+	AnalyzeSamples    int
+	PredictionSamples int
+	PredictionNumber  int
+	Enabled           bool // query the Forecaster; when disabled, or with the basic model, use Simple
+}
+
+// ConfigFromViper returns the Config of storm.adaptive and features.prediction. With storm.adaptive.ensemble
+// the model is named ensemble.
+func ConfigFromViper() Config {
+	model := viper.GetString("storm.adaptive.predictive_model")
+	if len(viper.GetStringSlice("storm.adaptive.ensemble")) > 0 {
+		model = "ensemble"
 	}
-	for i := index; i < len(topology.InputRate); i++ {
-		samples = append(samples, float64(topology.InputRate[i]))
-		//log.Printf("analyze: train: index={%d},sample={%v},\n", i, topology.InputRate[i])
+	return Config{
+		Model:             model,
+		AnalyzeSamples:    viper.GetInt("storm.adaptive.analyze_samples"),
+		PredictionSamples: viper.GetInt("storm.adaptive.prediction_samples"),
+		PredictionNumber:  viper.GetInt("storm.adaptive.prediction_number"),
+		Enabled:           viper.GetBool("features.prediction"),
+	}
+}
+
+// Predictor keeps the input rates predicted for one topology. Each controller owns its Predictor, so several
+// of them run in the same process.
+type Predictor struct {
+	config      Config
+	forecaster  Forecaster
+	predictions PredictionInput
+}
+
+// NewPredictor returns a Predictor with the given config and forecaster.
+func NewPredictor(config Config, forecaster Forecaster) *Predictor {
+	p := &Predictor{config: config, forecaster: forecaster}
+	p.Init()
+	return p
+}
+
+// Init clears the predictions, which start with analyze_samples periods predicted as zero.
+func (p *Predictor) Init() {
+	p.predictions.NameModel = p.config.Model
+	p.predictions.PredictedInput = make([]float64, p.config.AnalyzeSamples)
+}
+
+func (p *Predictor) Get() PredictionInput {
+	return p.predictions
+}
+
+// Predict appends the predictions of the next periods from the last prediction_samples input rates of the
+// topology.
+func (p *Predictor) Predict(topology *storm.Topology) {
+	p.Append(p.Forecast(p.Samples(topology)))
+}
+
+// Samples returns the input rates that the next prediction is made from.
+func (p *Predictor) Samples(topology *storm.Topology) []float64 {
+	var samples []float64
+	for _, inputRate := range topology.InputRate.Tail(p.config.PredictionSamples) {
+		samples = append(samples, float64(inputRate))
+		//log.Printf("analyze: train: sample={%v},\n", inputRate)
 	}
+	return samples
+}
 
+// Forecast predicts the next periods from the samples. It does not change the Predictor, so it runs while the
+// topology keeps being monitored.
+func (p *Predictor) Forecast(samples []float64) []float64 {
 	//log.Printf("[t=X] predict input : init prediction")
-	var resultsPrediction []float64
-	if viper.GetString("storm.adaptive.predictive_model") != "basic" {
-		resultsPrediction = GetPrediction(samples, viper.GetInt("storm.adaptive.prediction_number"), predictions.NameModel)
-	} else {
-		resultsPrediction = Simple(topology)
+	if p.config.Model != "basic" && p.config.Enabled {
+		return p.forecaster.Forecast(samples, p.config.PredictionNumber, p.predictions.NameModel)
 	}
+	return samples
+}
 
+// Append appends the predictions of a Forecast.
+func (p *Predictor) Append(resultsPrediction []float64) {
 	if len(resultsPrediction) > 0 {
-		predictions.PredictedInput = append(predictions.PredictedInput, resultsPrediction...)
+		p.predictions.PredictedInput = append(p.predictions.PredictedInput, resultsPrediction...)
 	}
 }
 
-func GetPredictedInputPeriod(period int) int64 {
-	if period >= len(predictions.PredictedInput) {
-		period = len(predictions.PredictedInput) - 1
+func (p *Predictor) PredictedInputPeriod(period int) int64 {
+	if period >= len(p.predictions.PredictedInput) {
+		period = len(p.predictions.PredictedInput) - 1
 	}
-	predictedInputPeriod := int64(predictions.PredictedInput[period])
+	predictedInputPeriod := int64(p.predictions.PredictedInput[period])
 	//log.Printf("predicted input period : %d perdiction={%v}", period, predictions[indexChosenPredictor])
 	return predictedInputPeriod
 }
+
+// Default is the Predictor behind the functions of the package.
+//
+// Deprecated: create a Predictor with NewPredictor.
+var Default = NewPredictor(Config{}, defaultAPI)
+
+// Deprecated: use Predictor.Get.
+func GetPred() PredictionInput {
+	return Default.Get()
+}
+
+// InitPrediction resets Default with the global config.
+//
+// Deprecated: use NewPredictor.
+func InitPrediction() {
+	Default = NewPredictor(ConfigFromViper(), defaultAPI)
+}
+
+// Deprecated: use Predictor.Predict.
+func PredictInput(topology *storm.Topology) {
+	Default.Predict(topology)
+}
+
+// Deprecated: use Predictor.PredictedInputPeriod.
+func GetPredictedInputPeriod(period int) int64 {
+	return Default.PredictedInputPeriod(period)
+}
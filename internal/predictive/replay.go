@@ -0,0 +1,24 @@
+package predictive
+
+import (
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/spf13/viper"
+)
+
+// Replay feeds a recorded input-rate trace through the configured predictive model, following the same
+// cadence as the analyze module, and returns the predicted input for each period of the trace.
+func Replay(inputRate []int64) []int64 {
+	predictor := NewPredictor(ConfigFromViper(), ForecasterFromViper(NewCatalog()))
+
+	topology := storm.Topology{InputRate: storm.NewHistory(viper.GetInt("storm.adaptive.prediction_samples"))}
+	predicted := make([]int64, len(inputRate))
+	for period := 0; period < len(inputRate); period++ {
+		if period > 0 && period%viper.GetInt("storm.adaptive.analyze_samples") == 0 {
+			predictor.Predict(&topology)
+		}
+		predicted[period] = predictor.PredictedInputPeriod(period)
+		topology.InputRate.Append(inputRate[period])
+	}
+
+	return predicted
+}
@@ -0,0 +1,66 @@
+package predictive
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/spf13/viper"
+)
+
+// Training is the training reported by a model of the Predictor API along with its predictions. The models of
+// py/app.py fit the samples of each request and report none; a model trained offline reports when, so that it is
+// flagged stale after predictor.max_model_age.
+type Training struct {
+	TrainedAt time.Time
+	Version   string
+}
+
+// trainings are the last Training reported by each model of an API
+type trainings struct {
+	lock sync.Mutex
+	last map[string]Training
+	// flagged are the models already flagged stale, so each one is logged once until it is trained again
+	flagged map[string]bool
+}
+
+func newTrainings() *trainings {
+	return &trainings{last: make(map[string]Training), flagged: make(map[string]bool)}
+}
+
+// update records the training reported by the model, when it reports one.
+func (t *trainings) update(model string, resp Response) {
+	if resp.TrainedAt.IsZero() {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if last, ok := t.last[model]; ok && last.Version != resp.Version {
+		log.Printf("predictor: model={%s},version={%s},trained_at={%v}\n", model, resp.Version, resp.TrainedAt)
+	}
+	if t.last[model].TrainedAt != resp.TrainedAt {
+		t.flagged[model] = false
+	}
+	t.last[model] = Training{TrainedAt: resp.TrainedAt, Version: resp.Version}
+}
+
+// stale reports whether the model was trained more than predictor.max_model_age hours ago, flagging it in the log
+// the first time. A model that reports no training is never stale.
+func (t *trainings) stale(model string) bool {
+	maxAge := time.Duration(viper.GetFloat64("predictor.max_model_age") * float64(time.Hour))
+	if maxAge <= 0 {
+		return false
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	training, ok := t.last[model]
+	if !ok || util.Now().Sub(training.TrainedAt) <= maxAge {
+		return false
+	}
+	if !t.flagged[model] {
+		t.flagged[model] = true
+		log.Printf("predictor: stale model={%s},version={%s},trained_at={%v}\n", model, training.Version, training.TrainedAt)
+	}
+	return true
+}
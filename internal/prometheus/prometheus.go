@@ -0,0 +1,192 @@
+package prometheus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/spf13/viper"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+type queryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		Result []struct {
+			Value []interface{} `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// query is the data of the templates of prometheus.queries.
+type query struct {
+	Topology  string
+	Component string
+	Stream    string
+}
+
+// Metrics wraps a cluster and reads the metrics of the topology from Prometheus instead of the cluster, with the
+// PromQL templates of prometheus.queries. The topology and the actuation are still the ones of the cluster.
+type Metrics struct {
+	storm.Cluster
+	url       string
+	templates map[string]*template.Template
+}
+
+func New(c storm.Cluster) (*Metrics, error) {
+	m := &Metrics{Cluster: c, url: strings.TrimSuffix(viper.GetString("prometheus.url"), "/"), templates: make(map[string]*template.Template)}
+	for _, name := range []string{"spout_emitted", "emitted", "executed", "execute_latency", "latency", "gpu_utilization", "gpu_memory"} {
+		if text := viper.GetString("prometheus.queries." + name); text != "" {
+			if t, err := template.New(name).Parse(text); err != nil {
+				return nil, fmt.Errorf("prometheus query %s: %v", name, err)
+			} else {
+				m.templates[name] = t
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m *Metrics) GetMetrics(topology storm.Topology) (bool, storm.TopologyMetrics) {
+	var metrics storm.TopologyMetrics
+	for _, spout := range topology.Spouts {
+		spoutMetrics := storm.SpoutMetrics{Id: spout.Name}
+		emitted, err := m.query("spout_emitted", query{Topology: topology.Id, Component: spout.Name})
+		if err != nil {
+			log.Printf("prometheus get metrics: %v\n", err)
+			return false, metrics
+		}
+		spoutMetrics.SpoutSummary = append(spoutMetrics.SpoutSummary, storm.SpoutSummary{Emitted: int(emitted), Window: ":all-time"})
+		for _, successor := range successors(topology, spout.Name) {
+			if emitted, err := m.query("emitted", query{Topology: topology.Id, Component: spout.Name, Stream: successor}); err != nil {
+				log.Printf("prometheus get metrics: %v\n", err)
+				return false, metrics
+			} else {
+				spoutMetrics.OutputStats = append(spoutMetrics.OutputStats, storm.SpoutOutputStats{Emitted: int(emitted), Stream: successor})
+			}
+		}
+		metrics.Spouts = append(metrics.Spouts, spoutMetrics)
+	}
+
+	for _, bolt := range topology.Bolts {
+		boltMetrics := storm.BoltMetrics{Id: bolt.Name}
+		for _, predecessor := range bolt.BoltsPredecessor {
+			boltMetrics.InputStats = append(boltMetrics.InputStats, storm.BoltInputStats{Component: predecessor})
+		}
+		executed, err := m.query("executed", query{Topology: topology.Id, Component: bolt.Name})
+		if err != nil {
+			log.Printf("prometheus get metrics: %v\n", err)
+			return false, metrics
+		}
+		latency, err := m.query("execute_latency", query{Topology: topology.Id, Component: bolt.Name})
+		if err != nil {
+			log.Printf("prometheus get metrics: %v\n", err)
+			return false, metrics
+		}
+		boltMetrics.BoltStats = append(boltMetrics.BoltStats, storm.BoltStats{
+			ExecuteLatency: strconv.FormatFloat(latency, 'f', 3, 64),
+			Window:         ":all-time",
+			Executed:       int64(executed),
+		})
+		if boltMetrics.GpuUtilization, boltMetrics.GpuMemory, err = m.queryGpu(topology.Id, bolt.Name); err != nil {
+			log.Printf("prometheus get metrics: %v\n", err)
+			return false, metrics
+		}
+		for _, successor := range successors(topology, bolt.Name) {
+			if emitted, err := m.query("emitted", query{Topology: topology.Id, Component: bolt.Name, Stream: successor}); err != nil {
+				log.Printf("prometheus get metrics: %v\n", err)
+				return false, metrics
+			} else {
+				boltMetrics.OutputStats = append(boltMetrics.OutputStats, storm.BoltOutputStats{Emitted: int64(emitted), Stream: successor})
+			}
+		}
+		metrics.Bolts = append(metrics.Bolts, boltMetrics)
+	}
+	return true, metrics
+}
+
+// GetLatency queries prometheus.queries.latency, or asks the cluster when it is empty.
+func (m *Metrics) GetLatency() float64 {
+	if _, ok := m.templates["latency"]; !ok {
+		return m.Cluster.GetLatency()
+	}
+	latency, err := m.query("latency", query{})
+	if err != nil {
+		log.Printf("prometheus get latency: %v\n", err)
+	}
+	return latency
+}
+
+// queryGpu queries the GPU utilization (0-100) and memory of the bolt, zero for the queries that are empty.
+func (m *Metrics) queryGpu(topologyId string, bolt string) (float64, float64, error) {
+	var utilization, memory float64
+	var err error
+	if _, ok := m.templates["gpu_utilization"]; ok {
+		if utilization, err = m.query("gpu_utilization", query{Topology: topologyId, Component: bolt}); err != nil {
+			return 0, 0, err
+		}
+	}
+	if _, ok := m.templates["gpu_memory"]; ok {
+		if memory, err = m.query("gpu_memory", query{Topology: topologyId, Component: bolt}); err != nil {
+			return 0, 0, err
+		}
+	}
+	return utilization, memory, nil
+}
+
+// query runs the template of the metric and returns the sum of the samples of the result.
+func (m *Metrics) query(name string, data query) (float64, error) {
+	t, ok := m.templates[name]
+	if !ok {
+		return 0, fmt.Errorf("prometheus: query %s is empty", name)
+	}
+	var promql bytes.Buffer
+	if err := t.Execute(&promql, data); err != nil {
+		return 0, err
+	}
+
+	res, err := http.Get(m.url + "/api/v1/query?query=" + url.QueryEscape(promql.String()))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	var response queryResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return 0, fmt.Errorf("prometheus query %s: %v", name, err)
+	}
+	if response.Status != "success" {
+		return 0, fmt.Errorf("prometheus query %s: %s", name, response.Error)
+	}
+
+	var value float64
+	for _, sample := range response.Data.Result {
+		if len(sample.Value) == 2 {
+			if s, ok := sample.Value[1].(string); ok {
+				v, _ := strconv.ParseFloat(s, 64)
+				value += v
+			}
+		}
+	}
+	return value, nil
+}
+
+// successors returns the bolts of the topology that have the component as predecessor.
+func successors(topology storm.Topology, component string) []string {
+	var successors []string
+	for _, bolt := range topology.Bolts {
+		for _, predecessor := range bolt.BoltsPredecessor {
+			if predecessor == component {
+				successors = append(successors, bolt.Name)
+			}
+		}
+	}
+	return successors
+}
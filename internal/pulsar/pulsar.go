@@ -0,0 +1,289 @@
+package pulsar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/spf13/viper"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type functionConfig struct {
+	Tenant      string                     `json:"tenant"`
+	Namespace   string                     `json:"namespace"`
+	Name        string                     `json:"name"`
+	Inputs      []string                   `json:"inputs,omitempty"`
+	InputSpecs  map[string]json.RawMessage `json:"inputSpecs,omitempty"`
+	Output      string                     `json:"output,omitempty"`
+	SubName     string                     `json:"subName,omitempty"`
+	Parallelism int64                      `json:"parallelism,omitempty"`
+}
+
+type functionStats struct {
+	ReceivedTotal              int64   `json:"receivedTotal"`
+	ProcessedSuccessfullyTotal int64   `json:"processedSuccessfullyTotal"`
+	AvgProcessLatency          float64 `json:"avgProcessLatency"`
+}
+
+type functionStatus struct {
+	NumInstances int64 `json:"numInstances"`
+	NumRunning   int64 `json:"numRunning"`
+}
+
+type topicStats struct {
+	MsgInCounter  int64 `json:"msgInCounter"`
+	Subscriptions map[string]struct {
+		MsgBacklog int64 `json:"msgBacklog"`
+	} `json:"subscriptions"`
+}
+
+// Pulsar is the Cluster of a pipeline of Pulsar Functions, managed through the admin REST API: the functions of
+// pulsar.functions are the bolts, chained by their input and output topics, and the input topics that no
+// function of the pipeline produces are the spouts. The tuples emitted to a function are the messages published in
+// its input topics, and the queue of a bolt is the backlog of its subscription to them. The replicas of a function are its
+// parallelism.
+type Pulsar struct {
+	url       string
+	functions map[string]functionConfig
+	order     []string
+	producers map[string]string // output topic -> function
+}
+
+func New() *Pulsar {
+	return &Pulsar{
+		url:       strings.TrimSuffix(viper.GetString("pulsar.admin_url"), "/"),
+		functions: make(map[string]functionConfig),
+		producers: make(map[string]string),
+	}
+}
+
+// GetTopologyId returns the namespace of the functions, since a pipeline has no id of its own.
+func (p *Pulsar) GetTopologyId() string {
+	return viper.GetString("pulsar.tenant") + "-" + viper.GetString("pulsar.namespace")
+}
+
+func (p *Pulsar) GetSummaryTopology(topologyId string) storm.SummaryTopology {
+	summary := storm.SummaryTopology{Id: topologyId, Name: topologyId}
+	p.order = nil
+	for _, name := range viper.GetStringSlice("pulsar.functions") {
+		var config functionConfig
+		if err := p.get(p.functionPath(name), &config); err != nil {
+			log.Printf("pulsar get function %s: %v\n", name, err)
+			time.Sleep(1 * time.Second)
+			return p.GetSummaryTopology(topologyId)
+		}
+		for topic := range config.InputSpecs {
+			config.Inputs = append(config.Inputs, topic)
+		}
+		sort.Strings(config.Inputs)
+		p.functions[name] = config
+		p.order = append(p.order, name)
+		if config.Output != "" {
+			p.producers[p.topic(config.Output)] = name
+		}
+		summary.Bolts = append(summary.Bolts, storm.SummaryBolt{BoltID: name})
+	}
+
+	spouts := make(map[string]bool)
+	for _, name := range p.order {
+		for _, input := range p.functions[name].Inputs {
+			if _, ok := p.producers[p.topic(input)]; !ok && !spouts[p.topic(input)] {
+				spouts[p.topic(input)] = true
+				summary.Spouts = append(summary.Spouts, storm.SummarySpout{SpoutId: p.topic(input)})
+			}
+		}
+	}
+	return summary
+}
+
+func (p *Pulsar) GetComponentBolt(topologyId, boltName string) storm.BoltMetrics {
+	metrics := storm.BoltMetrics{Id: boltName}
+	for _, input := range p.functions[boltName].Inputs {
+		metrics.InputStats = append(metrics.InputStats, storm.BoltInputStats{Component: p.predecessor(input)})
+	}
+	return metrics
+}
+
+func (p *Pulsar) GetMetrics(topology storm.Topology) (bool, storm.TopologyMetrics) {
+	var metrics storm.TopologyMetrics
+	topics := make(map[string]topicStats)
+	published := make(map[string]int64)
+	for _, name := range p.order {
+		for _, input := range p.functions[name].Inputs {
+			topic := p.topic(input)
+			if _, ok := topics[topic]; ok {
+				continue
+			}
+			var stats topicStats
+			if err := p.get("/admin/v2/"+strings.Replace(topic, "://", "/", 1)+"/stats", &stats); err != nil {
+				log.Printf("pulsar get metrics: %v\n", err)
+				return false, metrics
+			}
+			topics[topic] = stats
+			published[topic] = stats.MsgInCounter
+		}
+	}
+
+	for _, spout := range topology.Spouts {
+		spoutMetrics := storm.SpoutMetrics{Id: spout.Name}
+		spoutMetrics.SpoutSummary = append(spoutMetrics.SpoutSummary, storm.SpoutSummary{Emitted: int(published[spout.Name]), Window: ":all-time"})
+		for _, successor := range p.successors(spout.Name) {
+			spoutMetrics.OutputStats = append(spoutMetrics.OutputStats, storm.SpoutOutputStats{Emitted: int(published[spout.Name]), Stream: successor})
+		}
+		metrics.Spouts = append(metrics.Spouts, spoutMetrics)
+	}
+
+	for _, bolt := range topology.Bolts {
+		var stats functionStats
+		if err := p.get(p.functionPath(bolt.Name)+"/stats", &stats); err != nil {
+			log.Printf("pulsar get metrics: %v\n", err)
+			return false, metrics
+		}
+		boltMetrics := p.GetComponentBolt(topology.Id, bolt.Name)
+		boltMetrics.BoltStats = append(boltMetrics.BoltStats, storm.BoltStats{
+			ExecuteLatency: strconv.FormatFloat(stats.AvgProcessLatency, 'f', 3, 64),
+			Window:         ":all-time",
+			Executed:       stats.ProcessedSuccessfullyTotal,
+		})
+		var backlog int64
+		for _, input := range p.functions[bolt.Name].Inputs {
+			backlog += topics[p.topic(input)].Subscriptions[p.subscription(bolt.Name)].MsgBacklog
+		}
+		boltMetrics.Backlog = &backlog
+		if output := p.functions[bolt.Name].Output; output != "" {
+			for _, successor := range p.successors(p.topic(output)) {
+				boltMetrics.OutputStats = append(boltMetrics.OutputStats, storm.BoltOutputStats{Emitted: published[p.topic(output)], Stream: successor})
+			}
+		}
+		metrics.Bolts = append(metrics.Bolts, boltMetrics)
+	}
+	return true, metrics
+}
+
+func (p *Pulsar) GetLatency() float64 {
+	return util.GetLatency()
+}
+
+// GetStatus reports the pipeline as rebalancing while some instance of a function is not running yet, as after
+// its parallelism is updated.
+func (p *Pulsar) GetStatus(topologyId string) (string, error) {
+	for _, name := range p.order {
+		var status functionStatus
+		if err := p.get(p.functionPath(name)+"/status", &status); err != nil {
+			return "", err
+		}
+		if status.NumRunning < status.NumInstances {
+			return storm.StatusRebalancing, nil
+		}
+	}
+	return storm.StatusActive, nil
+}
+
+// SetReplicas updates the parallelism of the function.
+func (p *Pulsar) SetReplicas(bolt string, replicas int64) error {
+	config := functionConfig{
+		Tenant:      viper.GetString("pulsar.tenant"),
+		Namespace:   viper.GetString("pulsar.namespace"),
+		Name:        bolt,
+		Parallelism: replicas,
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="functionConfig"`)
+	header.Set("Content-Type", "application/json")
+	if part, err := writer.CreatePart(header); err != nil {
+		return err
+	} else if _, err := part.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, p.url+p.functionPath(bolt), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("pulsar update function %s: %s: %s", bolt, res.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// predecessor returns the function that produces the topic, or the topic itself when it is a spout.
+func (p *Pulsar) predecessor(topic string) string {
+	if function, ok := p.producers[p.topic(topic)]; ok {
+		return function
+	}
+	return p.topic(topic)
+}
+
+// successors returns the functions whose input is the topic.
+func (p *Pulsar) successors(topic string) []string {
+	var successors []string
+	for _, name := range p.order {
+		for _, input := range p.functions[name].Inputs {
+			if p.topic(input) == topic {
+				successors = append(successors, name)
+			}
+		}
+	}
+	return successors
+}
+
+// topic returns the full name of a topic, persistent://tenant/namespace/topic for a short name.
+func (p *Pulsar) topic(name string) string {
+	if strings.Contains(name, "://") {
+		return name
+	}
+	return "persistent://" + viper.GetString("pulsar.tenant") + "/" + viper.GetString("pulsar.namespace") + "/" + name
+}
+
+// subscription returns the subscription of the function to its input topics, tenant/namespace/name unless the
+// function sets its own.
+func (p *Pulsar) subscription(name string) string {
+	if subName := p.functions[name].SubName; subName != "" {
+		return subName
+	}
+	return viper.GetString("pulsar.tenant") + "/" + viper.GetString("pulsar.namespace") + "/" + name
+}
+
+func (p *Pulsar) functionPath(name string) string {
+	return "/admin/v3/functions/" + viper.GetString("pulsar.tenant") + "/" + viper.GetString("pulsar.namespace") + "/" + name
+}
+
+func (p *Pulsar) get(path string, out interface{}) error {
+	res, err := http.Get(p.url + path)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("pulsar get %s: %s", path, res.Status)
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,215 @@
+package simulator
+
+import (
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"math"
+	"strconv"
+)
+
+// BoltSpec describes a simulated bolt.
+type BoltSpec struct {
+	Name           string   `mapstructure:"name"`
+	ExecuteLatency float64  `mapstructure:"execute_latency"` // ms per tuple and replica
+	Selectivity    float64  `mapstructure:"selectivity"`     // tuples emitted per executed tuple
+	Predecessors   []string `mapstructure:"predecessors"`
+}
+
+type bolt struct {
+	BoltSpec
+	replicas int64
+	queue    float64
+	executed float64 // cumulative
+	emitted  float64 // cumulative, emitted to each successor
+}
+
+// Simulator is a Cluster that models the bolts of a topology as queues served by their replicas, fed with an
+// input-rate trace. Every call to GetMetrics advances the simulation by one time window.
+type Simulator struct {
+	topologyId string
+	spout      string
+	window     float64 // seconds
+	trace      []int64
+	bolts      []*bolt
+	t          int
+	emitted    float64 // cumulative, emitted by the spout
+	latency    float64
+	completed  float64 // cumulative, latency of the emitted tuples, for their complete latency
+}
+
+// New creates a simulator of a topology with one spout and the given bolts, listed in topological order.
+func New(topologyId string, spout string, specs []BoltSpec, trace []int64, window float64) (*Simulator, error) {
+	s := &Simulator{
+		topologyId: topologyId,
+		spout:      spout,
+		window:     window,
+		trace:      trace,
+	}
+
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("simulator: topology without bolts")
+	}
+	names := map[string]bool{spout: true}
+	for _, spec := range specs {
+		if spec.ExecuteLatency <= 0 {
+			return nil, fmt.Errorf("simulator: bolt %s: execute_latency must be positive", spec.Name)
+		}
+		if len(spec.Predecessors) == 0 {
+			return nil, fmt.Errorf("simulator: bolt %s: without predecessors", spec.Name)
+		}
+		for _, predecessor := range spec.Predecessors {
+			if !names[predecessor] {
+				return nil, fmt.Errorf("simulator: bolt %s: unknown predecessor %s (bolts must be in topological order)", spec.Name, predecessor)
+			}
+		}
+		if spec.Selectivity <= 0 {
+			spec.Selectivity = 1
+		}
+		names[spec.Name] = true
+		s.bolts = append(s.bolts, &bolt{BoltSpec: spec, replicas: 1})
+	}
+
+	return s, nil
+}
+
+// Windows returns the number of time windows of the trace.
+func (s *Simulator) Windows() int {
+	return len(s.trace)
+}
+
+func (s *Simulator) GetTopologyId() string {
+	return s.topologyId
+}
+
+func (s *Simulator) GetSummaryTopology(topologyId string) storm.SummaryTopology {
+	summary := storm.SummaryTopology{
+		Id:     s.topologyId,
+		Name:   s.topologyId,
+		Spouts: []storm.SummarySpout{{SpoutId: s.spout}},
+	}
+	for _, b := range s.bolts {
+		summary.Bolts = append(summary.Bolts, storm.SummaryBolt{BoltID: b.Name, Executors: b.replicas})
+	}
+	return summary
+}
+
+func (s *Simulator) GetComponentBolt(topologyId, boltName string) storm.BoltMetrics {
+	var metrics storm.BoltMetrics
+	for _, b := range s.bolts {
+		if b.Name == boltName {
+			metrics = s.boltMetrics(b)
+		}
+	}
+	return metrics
+}
+
+func (s *Simulator) GetMetrics(topology storm.Topology) (bool, storm.TopologyMetrics) {
+	s.step()
+
+	// every tuple is acked, and the complete latency is the all-time mean, as in Storm
+	var completeLatency float64
+	if s.emitted > 0 {
+		completeLatency = s.completed / s.emitted
+	}
+	var metrics storm.TopologyMetrics
+	spout := storm.SpoutMetrics{
+		Id:           s.spout,
+		SpoutSummary: []storm.SpoutSummary{{Emitted: int(s.emitted), Acked: int(s.emitted), CompleteLatency: completeLatency, Window: ":all-time"}},
+	}
+	for _, successor := range s.successors(s.spout) {
+		spout.OutputStats = append(spout.OutputStats, storm.SpoutOutputStats{
+			Emitted:         int(s.emitted),
+			CompleteLatency: strconv.FormatFloat(s.latency, 'f', 3, 64),
+			Stream:          successor,
+		})
+	}
+	metrics.Spouts = append(metrics.Spouts, spout)
+
+	for _, b := range s.bolts {
+		metrics.Bolts = append(metrics.Bolts, s.boltMetrics(b))
+	}
+	return true, metrics
+}
+
+func (s *Simulator) GetLatency() float64 {
+	return s.latency
+}
+
+func (s *Simulator) SetReplicas(boltName string, replicas int64) error {
+	for _, b := range s.bolts {
+		if b.Name == boltName {
+			b.replicas = replicas
+			return nil
+		}
+	}
+	return fmt.Errorf("simulator: unknown bolt %s", boltName)
+}
+
+// GetStatus reports the topology as active, since the replicas of the simulator change at once.
+func (s *Simulator) GetStatus(topologyId string) (string, error) {
+	return storm.StatusActive, nil
+}
+
+// step advances the simulation by one time window: the spout emits the rate of the trace, and each bolt
+// executes as many queued tuples as its replicas can in the window.
+func (s *Simulator) step() {
+	var rate float64
+	if len(s.trace) > 0 {
+		rate = float64(s.trace[s.t%len(s.trace)])
+	}
+	s.t++
+	s.emitted += rate
+
+	arrivals := map[string]float64{s.spout: rate}
+	s.latency = 0
+	for _, b := range s.bolts {
+		var input float64
+		for _, predecessor := range b.Predecessors {
+			input += arrivals[predecessor]
+		}
+
+		capacity := float64(b.replicas) * s.window * 1000 / b.ExecuteLatency
+		b.queue += input
+		executed := math.Min(b.queue, capacity)
+		b.queue -= executed
+		b.executed += executed
+
+		output := executed * b.Selectivity
+		b.emitted += output
+		arrivals[b.Name] = output
+
+		// Execution time plus the time to drain the queue ahead of a tuple
+		s.latency += b.ExecuteLatency + b.queue/capacity*s.window*1000
+	}
+	s.completed += rate * s.latency
+}
+
+func (s *Simulator) successors(component string) []string {
+	var successors []string
+	for _, b := range s.bolts {
+		for _, predecessor := range b.Predecessors {
+			if predecessor == component {
+				successors = append(successors, b.Name)
+			}
+		}
+	}
+	return successors
+}
+
+func (s *Simulator) boltMetrics(b *bolt) storm.BoltMetrics {
+	metrics := storm.BoltMetrics{
+		Id: b.Name,
+		BoltStats: []storm.BoltStats{{
+			ExecuteLatency: strconv.FormatFloat(b.ExecuteLatency, 'f', 3, 64),
+			Window:         ":all-time",
+			Executed:       int64(b.executed),
+		}},
+	}
+	for _, predecessor := range b.Predecessors {
+		metrics.InputStats = append(metrics.InputStats, storm.BoltInputStats{Component: predecessor})
+	}
+	for _, successor := range s.successors(b.Name) {
+		metrics.OutputStats = append(metrics.OutputStats, storm.BoltOutputStats{Emitted: int64(b.emitted), Stream: successor})
+	}
+	return metrics
+}
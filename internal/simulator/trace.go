@@ -0,0 +1,56 @@
+package simulator
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ReadTrace reads an input-rate trace: either one rate per line, or a csv whose header has an input_rate column
+// (such as the Topology.csv saved by a run).
+func ReadTrace(filename string) ([]int64, error) {
+	var trace []int64
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return trace, err
+	}
+	defer f.Close()
+
+	column := 0
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Split(strings.TrimSpace(scanner.Text()), ",")
+		if fields[0] == "" {
+			continue
+		}
+
+		if line == 1 {
+			if _, err := strconv.ParseFloat(fields[0], 64); err != nil {
+				column = -1
+				for i := range fields {
+					if fields[i] == "input_rate" {
+						column = i
+					}
+				}
+				if column < 0 {
+					return trace, fmt.Errorf("trace %s: no input_rate column", filename)
+				}
+				continue
+			}
+		}
+
+		if column >= len(fields) {
+			return trace, fmt.Errorf("trace %s: line %d: missing input_rate", filename, line)
+		}
+		rate, err := strconv.ParseFloat(fields[column], 64)
+		if err != nil {
+			return trace, fmt.Errorf("trace %s: line %d: %v", filename, line, err)
+		}
+		trace = append(trace, int64(rate))
+	}
+
+	return trace, scanner.Err()
+}
@@ -0,0 +1,243 @@
+package spark
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dwladdimiroc/sps-storm/internal/storm"
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"github.com/spf13/viper"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type application struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type executor struct {
+	Id       string `json:"id"`
+	IsActive bool   `json:"isActive"`
+}
+
+type metricsServlet struct {
+	Gauges map[string]struct {
+		Value interface{} `json:"value"`
+	} `json:"gauges"`
+}
+
+// progress is the progress of the streaming query in the last micro-batch, from the gauges of the query
+// (spark.sql.streaming.metricsEnabled).
+type progress struct {
+	inputRate      float64 // rows per second that arrived
+	processingRate float64 // rows per second processed while a batch runs
+	latency        float64 // duration (ms) of the last batch
+}
+
+// Spark is the Cluster of a Spark Structured Streaming query, read from the REST API and the metrics servlet of
+// the driver UI: the source of the query is the spout and the query is its single bolt, whose replicas are the
+// executors of the application. Spark has no API to change them from outside the driver, so the replicas are
+// written in Redis as signals (spark.redis_prefix + executors and shuffle_partitions), which a listener of the
+// query applies with requestTotalExecutors (deployments/spark/sps_listener.py).
+type Spark struct {
+	url       string
+	appId     string
+	query     string
+	rows      int64   // rows of the query since the start, integrated from the input rate of each window
+	latency   float64 // duration (ms) of the last micro-batch
+	executors int64
+}
+
+func New() *Spark {
+	return &Spark{
+		url:   strings.TrimSuffix(viper.GetString("spark.ui_url"), "/"),
+		query: viper.GetString("spark.query"),
+	}
+}
+
+// GetTopologyId returns spark.app_id, or the first application of the driver.
+func (s *Spark) GetTopologyId() string {
+	if appId := viper.GetString("spark.app_id"); appId != "" {
+		s.appId = appId
+		return appId
+	}
+	var applications []application
+	if err := s.get("/api/v1/applications", &applications); err != nil {
+		log.Printf("spark get applications: %v\n", err)
+	}
+	if len(applications) > 0 {
+		s.appId = applications[0].Id
+		return s.appId
+	}
+	time.Sleep(1 * time.Second)
+	return s.GetTopologyId()
+}
+
+func (s *Spark) GetSummaryTopology(topologyId string) storm.SummaryTopology {
+	s.appId = topologyId
+	if _, err := s.progress(); err != nil {
+		log.Printf("spark get summary topology: %v\n", err)
+		time.Sleep(1 * time.Second)
+		return s.GetSummaryTopology(topologyId)
+	}
+	return storm.SummaryTopology{
+		Id:     topologyId,
+		Name:   s.query,
+		Spouts: []storm.SummarySpout{{SpoutId: viper.GetString("spark.source")}},
+		Bolts:  []storm.SummaryBolt{{BoltID: s.query}},
+	}
+}
+
+func (s *Spark) GetComponentBolt(topologyId, boltName string) storm.BoltMetrics {
+	return storm.BoltMetrics{Id: boltName, InputStats: []storm.BoltInputStats{{Component: viper.GetString("spark.source")}}}
+}
+
+func (s *Spark) GetMetrics(topology storm.Topology) (bool, storm.TopologyMetrics) {
+	var metrics storm.TopologyMetrics
+	p, err := s.progress()
+	if err != nil {
+		log.Printf("spark get metrics: %v\n", err)
+		return false, metrics
+	}
+	executors, err := s.activeExecutors()
+	if err != nil {
+		log.Printf("spark get metrics: %v\n", err)
+		return false, metrics
+	}
+	s.executors = executors
+	s.latency = p.latency
+	s.rows += int64(p.inputRate * viper.GetFloat64("storm.adaptive.time_window_size"))
+
+	// The time of a row in an executor, from the rate of the query while its batches run on all the executors
+	var executeLatency float64
+	if p.processingRate > 0 {
+		executeLatency = 1000 * float64(executors) / p.processingRate
+	}
+
+	for _, spout := range topology.Spouts {
+		spoutMetrics := storm.SpoutMetrics{Id: spout.Name}
+		spoutMetrics.SpoutSummary = append(spoutMetrics.SpoutSummary, storm.SpoutSummary{Emitted: int(s.rows), Window: ":all-time"})
+		spoutMetrics.OutputStats = append(spoutMetrics.OutputStats, storm.SpoutOutputStats{Emitted: int(s.rows), Stream: s.query})
+		metrics.Spouts = append(metrics.Spouts, spoutMetrics)
+	}
+	for _, bolt := range topology.Bolts {
+		boltMetrics := s.GetComponentBolt(topology.Id, bolt.Name)
+		boltMetrics.BoltStats = append(boltMetrics.BoltStats, storm.BoltStats{
+			ExecuteLatency: strconv.FormatFloat(executeLatency, 'f', 3, 64),
+			Window:         ":all-time",
+			Executed:       s.rows,
+		})
+		metrics.Bolts = append(metrics.Bolts, boltMetrics)
+	}
+	return true, metrics
+}
+
+// GetLatency returns the duration (ms) of the last micro-batch.
+func (s *Spark) GetLatency() float64 {
+	return s.latency
+}
+
+// GetStatus reports the query as active: the executors change between micro-batches, with no rebalance.
+func (s *Spark) GetStatus(topologyId string) (string, error) {
+	return storm.StatusActive, nil
+}
+
+// SetReplicas writes the executors of the query and the shuffle partitions that go with them
+// (spark.partitions_per_executor per executor) in Redis, for the listener of the driver.
+func (s *Spark) SetReplicas(bolt string, replicas int64) error {
+	if bolt != s.query {
+		return fmt.Errorf("spark: unknown query %s", bolt)
+	}
+	prefix := viper.GetString("spark.redis_prefix")
+	if err := util.RedisSet(prefix+"executors", strconv.FormatInt(replicas, 10)); err != nil {
+		return err
+	}
+	partitions := replicas * viper.GetInt64("spark.partitions_per_executor")
+	return util.RedisSet(prefix+"shuffle_partitions", strconv.FormatInt(partitions, 10))
+}
+
+// progress reads the gauges of the streaming query, named <namespace>.driver.spark.streaming.<query>.<gauge>.
+// When spark.query is empty, the first query of the servlet, by name, is used.
+func (s *Spark) progress() (progress, error) {
+	var servlet metricsServlet
+	if err := s.get("/metrics/json/", &servlet); err != nil {
+		return progress{}, err
+	}
+
+	var names []string
+	for name := range servlet.Gauges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var p progress
+	found := false
+	for _, name := range names {
+		gauge := servlet.Gauges[name]
+		i := strings.Index(name, ".spark.streaming.")
+		if i < 0 {
+			continue
+		}
+		rest := name[i+len(".spark.streaming."):]
+		j := strings.LastIndex(rest, ".")
+		if j < 0 {
+			continue
+		}
+		query, metric := rest[:j], rest[j+1:]
+		if s.query == "" {
+			s.query = query
+		}
+		if query != s.query {
+			continue
+		}
+		value, ok := gauge.Value.(float64)
+		if !ok {
+			continue
+		}
+		switch metric {
+		case "inputRate-total":
+			p.inputRate = value
+			found = true
+		case "processingRate-total":
+			p.processingRate = value
+		case "latency":
+			p.latency = value
+		}
+	}
+	if !found {
+		return p, fmt.Errorf("spark: no metrics of the streaming query %q (is spark.sql.streaming.metricsEnabled set?)", s.query)
+	}
+	return p, nil
+}
+
+func (s *Spark) activeExecutors() (int64, error) {
+	var executors []executor
+	if err := s.get("/api/v1/applications/"+s.appId+"/executors", &executors); err != nil {
+		return 0, err
+	}
+	var active int64
+	for _, e := range executors {
+		if e.Id != "driver" && e.IsActive {
+			active++
+		}
+	}
+	return active, nil
+}
+
+func (s *Spark) get(path string, out interface{}) error {
+	res, err := http.Get(s.url + path)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("spark get %s: %s", path, res.Status)
+	}
+	return json.Unmarshal(data, out)
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -85,17 +86,122 @@ func GetSummaryTopology(topologyId string) SummaryTopology {
 	}
 }
 
+// GetTopologyStatus returns the status of the topology in the Storm UI (ACTIVE, INACTIVE, REBALANCING or KILLED).
+// Unlike GetSummaryTopology it does not retry, so a failed request is left to the next window.
+func GetTopologyStatus(topologyId string) (string, error) {
+	var summaryTopology SummaryTopology
+
+	client := &http.Client{Timeout: time.Duration(viper.GetInt("nimbus.timeout")) * time.Millisecond}
+	res, err := client.Get(parseURL(NimbusSummaryTopologyBaseURL, topologyId))
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("storm get topology status: %s", res.Status)
+	}
+	if err := json.Unmarshal(data, &summaryTopology); err != nil {
+		return "", err
+	}
+	return summaryTopology.Status, nil
+}
+
+// lastSpouts and lastBolts are the last metrics polled of each component, keyed by topology/component
+var lastSpouts = make(map[string]SpoutMetrics)
+var lastBolts = make(map[string]BoltMetrics)
+var lastComponentsLock sync.Mutex
+
+// GetMetrics polls the metrics of the components of the topology with nimbus.workers concurrent requests, each
+// one with a timeout of nimbus.timeout. Up to nimbus.max_failed_components components may fail in a window: a
+// failed component repeats its last metrics, so its counters measure nothing in this window and the next window
+// measures the tuples of both. The window fails when more components failed, or when a failed component has no
+// metrics yet.
 func GetMetrics(topology Topology) (bool, TopologyMetrics) {
 	var metricsTopology TopologyMetrics
-	for _, spout := range topology.Spouts {
-		metricsTopology.Spouts = append(metricsTopology.Spouts, GetComponentSpout(topology.Id, spout.Name))
+	client := &http.Client{Timeout: time.Duration(viper.GetInt("nimbus.timeout")) * time.Millisecond}
+
+	spouts := make([]SpoutMetrics, len(topology.Spouts))
+	bolts := make([]BoltMetrics, len(topology.Bolts))
+	errs := make([]error, len(topology.Spouts)+len(topology.Bolts))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	workers := viper.GetInt("nimbus.workers")
+	if workers < 1 {
+		workers = 1
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if i < len(spouts) {
+					errs[i] = getComponent(client, topology.Id, topology.Spouts[i].Name, &spouts[i])
+				} else {
+					errs[i] = getComponent(client, topology.Id, topology.Bolts[i-len(spouts)].Name, &bolts[i-len(spouts)])
+				}
+			}
+		}()
 	}
-	for _, bolt := range topology.Bolts {
-		metricsTopology.Bolts = append(metricsTopology.Bolts, GetComponentBolt(topology.Id, bolt.Name))
+	for i := range errs {
+		jobs <- i
 	}
+	close(jobs)
+	wg.Wait()
+
+	lastComponentsLock.Lock()
+	defer lastComponentsLock.Unlock()
+	var failed int
+	ok := true
+	for i := range spouts {
+		key := topology.Id + "/" + topology.Spouts[i].Name
+		if err := errs[i]; err == nil {
+			lastSpouts[key] = spouts[i]
+		} else {
+			failed++
+			fmt.Printf("storm get metrics: %v\n", err)
+			last, found := lastSpouts[key]
+			spouts[i], ok = last, ok && found
+		}
+	}
+	for i := range bolts {
+		key := topology.Id + "/" + topology.Bolts[i].Name
+		if err := errs[len(spouts)+i]; err == nil {
+			lastBolts[key] = bolts[i]
+		} else {
+			failed++
+			fmt.Printf("storm get metrics: %v\n", err)
+			last, found := lastBolts[key]
+			bolts[i], ok = last, ok && found
+		}
+	}
+	if !ok || failed > viper.GetInt("nimbus.max_failed_components") {
+		return false, metricsTopology
+	}
+	metricsTopology.Spouts = spouts
+	metricsTopology.Bolts = bolts
 	return true, metricsTopology
 }
 
+func getComponent(client *http.Client, topologyId, component string, out interface{}) error {
+	res, err := client.Get(parseComponentURL(NimbusComponentsBaseURL, topologyId, component))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("component %s: %v", component, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("component %s: %s", component, res.Status)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("component %s: %v", component, err)
+	}
+	return nil
+}
+
 func GetComponentBolt(topologyId, boltName string) BoltMetrics {
 	var boltMetrics BoltMetrics
 
@@ -0,0 +1,66 @@
+package storm
+
+import (
+	"github.com/dwladdimiroc/sps-storm/internal/util"
+	"strconv"
+)
+
+// Cluster is the stream processing system managed by the adaptive system: where the topology and its metrics
+// are read from, and where the replicas of the bolts are written.
+type Cluster interface {
+	GetTopologyId() string
+	GetSummaryTopology(topologyId string) SummaryTopology
+	GetComponentBolt(topologyId, boltName string) BoltMetrics
+	GetMetrics(topology Topology) (bool, TopologyMetrics)
+	GetLatency() float64
+	SetReplicas(bolt string, replicas int64) error
+	GetStatus(topologyId string) (string, error)
+}
+
+// BatchCluster is a Cluster that changes the replicas of several bolts in a single update, for the systems where
+// each update restarts the topology (Heron). The adaptive system sends it every change of a plan at once.
+type BatchCluster interface {
+	Cluster
+	SetReplicasBatch(replicas map[string]int64) error
+}
+
+// Status of the topology in the stream processing system, as reported by GetStatus. The systems with no status of
+// their own report StatusActive.
+const (
+	StatusActive      = "ACTIVE"
+	StatusInactive    = "INACTIVE"
+	StatusRebalancing = "REBALANCING"
+	StatusKilled      = "KILLED"
+)
+
+// Storm is the Cluster of a Storm deployment: the metrics come from the Storm UI REST API and the latency from
+// the REST server, and the replicas are written in Redis, where the topology reads them.
+type Storm struct{}
+
+func (Storm) GetTopologyId() string {
+	return GetTopologyId()
+}
+
+func (Storm) GetSummaryTopology(topologyId string) SummaryTopology {
+	return GetSummaryTopology(topologyId)
+}
+
+func (Storm) GetComponentBolt(topologyId, boltName string) BoltMetrics {
+	return GetComponentBolt(topologyId, boltName)
+}
+
+func (Storm) GetMetrics(topology Topology) (bool, TopologyMetrics) {
+	return GetMetrics(topology)
+}
+
+func (Storm) GetLatency() float64 {
+	return util.GetLatency()
+}
+
+func (Storm) SetReplicas(bolt string, replicas int64) error {
+	return util.RedisSet(bolt, strconv.FormatInt(replicas, 10))
+}
+
+func (Storm) GetStatus(topologyId string) (string, error) {
+	return GetTopologyStatus(topologyId)
+}
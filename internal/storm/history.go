@@ -0,0 +1,57 @@
+package storm
+
+// History is the input rate of the last windows, capped to a number of windows so that long runs do not keep
+// every window since the start. The zero History keeps every window.
+type History struct {
+	values   []int64
+	capacity int
+}
+
+// NewHistory returns an empty History of the last capacity windows.
+func NewHistory(capacity int) History {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return History{values: make([]int64, 0, 2*capacity), capacity: capacity}
+}
+
+// Append adds the input rate of a window, forgetting the oldest ones beyond the capacity. The values are moved
+// to the front of the buffer once it doubles the capacity, so the buffer is allocated once.
+func (h *History) Append(value int64) {
+	if h.capacity > 0 && len(h.values) == 2*h.capacity {
+		n := copy(h.values, h.values[h.capacity:])
+		h.values = h.values[:n]
+	}
+	h.values = append(h.values, value)
+}
+
+// Clone returns a copy of the history that does not share its buffer, which Append rewrites in place.
+func (h *History) Clone() History {
+	clone := History{values: make([]int64, len(h.values), cap(h.values)), capacity: h.capacity}
+	copy(clone.values, h.values)
+	return clone
+}
+
+// Len returns the number of windows kept.
+func (h *History) Len() int {
+	if h.capacity > 0 && len(h.values) > h.capacity {
+		return h.capacity
+	}
+	return len(h.values)
+}
+
+// Last returns the input rate of the last window, 0 when it is empty.
+func (h *History) Last() int64 {
+	if len(h.values) == 0 {
+		return 0
+	}
+	return h.values[len(h.values)-1]
+}
+
+// Tail returns the input rate of the last n windows kept, from the oldest.
+func (h *History) Tail(n int) []int64 {
+	if n > h.Len() {
+		n = h.Len()
+	}
+	return h.values[len(h.values)-n:]
+}
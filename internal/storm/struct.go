@@ -10,20 +10,25 @@ type SummaryTopologies struct {
 }
 
 type SummaryTopology struct {
-	Name string `json:"name"`
-	Id   string `json:"id"`
+	Name   string `json:"name"`
+	Id     string `json:"id"`
+	Status string `json:"status"`
 
-	Spouts []struct {
-		SpoutId string `json:"spoutId"`
-	} `json:"spouts"`
-
-	Bolts []struct {
-		BoltID string `json:"boltId"`
-	} `json:"bolts"`
+	Spouts []SummarySpout `json:"spouts"`
+	Bolts  []SummaryBolt  `json:"bolts"`
 
 	Error string `json:"error"`
 }
 
+type SummarySpout struct {
+	SpoutId string `json:"spoutId"`
+}
+
+type SummaryBolt struct {
+	BoltID    string `json:"boltId"`
+	Executors int64  `json:"executors"`
+}
+
 type TopologyMetrics struct {
 	Spouts []SpoutMetrics `json:"spouts"`
 	Bolts  []BoltMetrics  `json:"bolts"`
@@ -32,34 +37,50 @@ type TopologyMetrics struct {
 type BoltMetrics struct {
 	Id string `json:"id"`
 
-	InputStats []struct {
-		Component string `json:"component"` //InputId
-	} `json:"inputStats"`
+	InputStats  []BoltInputStats  `json:"inputStats"`
+	BoltStats   []BoltStats       `json:"boltStats"`
+	OutputStats []BoltOutputStats `json:"outputStats"`
+
+	// GPU of the replicas of the bolt, not in the Storm UI: read from prometheus.queries.gpu_*
+	GpuUtilization float64 `json:"gpuUtilization,omitempty"`
+	GpuMemory      float64 `json:"gpuMemory,omitempty"`
+	// Backlog of the bolt in the cluster, not in the Storm UI: the messages of a Pulsar function not acknowledged
+	// yet. When it is set, it is the queue of the bolt instead of the input not executed
+	Backlog *int64 `json:"backlog,omitempty"`
+}
+
+type BoltInputStats struct {
+	Component string `json:"component"` //InputId
+}
 
-	BoltStats []struct {
-		ExecuteLatency string `json:"executeLatency"`
-		Window         string `json:"window"`
-		Executed       int64  `json:"executed"`
-	} `json:"boltStats"`
+type BoltStats struct {
+	ExecuteLatency string `json:"executeLatency"`
+	Window         string `json:"window"`
+	Executed       int64  `json:"executed"`
+}
 
-	OutputStats []struct {
-		Emitted int64  `json:"emitted"`
-		Stream  string `json:"stream"`
-	} `json:"outputStats"`
+type BoltOutputStats struct {
+	Emitted int64  `json:"emitted"`
+	Stream  string `json:"stream"`
 }
 
 type SpoutMetrics struct {
 	Id string `json:"id"`
 
-	SpoutSummary []struct {
-		Emitted         int     `json:"emitted"`
-		CompleteLatency float64 `json:"completeLatency"`
-		Window          string  `json:"window"` //:all-time
-	} `json:"spoutSummary"`
-
-	OutputStats []struct {
-		Emitted         int    `json:"emitted"`
-		CompleteLatency string `json:"completeLatency"`
-		Stream          string `json:"stream"` // Bolt Id
-	} `json:"outputStats"`
+	SpoutSummary []SpoutSummary     `json:"spoutSummary"`
+	OutputStats  []SpoutOutputStats `json:"outputStats"`
+}
+
+type SpoutSummary struct {
+	Emitted         int     `json:"emitted"`
+	Acked           int     `json:"acked"`
+	Failed          int     `json:"failed"`
+	CompleteLatency float64 `json:"completeLatency"`
+	Window          string  `json:"window"` //:all-time
+}
+
+type SpoutOutputStats struct {
+	Emitted         int    `json:"emitted"`
+	CompleteLatency string `json:"completeLatency"`
+	Stream          string `json:"stream"` // Bolt Id
 }
@@ -3,11 +3,8 @@ package storm
 import (
 	"fmt"
 	"github.com/dwladdimiroc/sps-storm/internal/util"
-	"github.com/montanaflynn/stats"
 	"github.com/spf13/viper"
 	"log"
-	"math"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -23,11 +20,15 @@ type Bolt struct {
 	Queue                           int64     `csv:"queue"`
 	PredictionQueue                 int64     `csv:"-"`
 	ExecutedTimeAvg                 float64   `csv:"executed_time_avg"`
-	ExecutedTimeAvgSamples          []float64 `csv:"-"`
+	ExecutedTimeAvgSamples          util.Ring `csv:"-"`
 	ExecutedTimeBenchmarkAvg        float64   `csv:"executed_time_benchmark_avg"`
-	ExecutedTimeBenchmarkAvgSamples []float64 `csv:"-"`
+	ExecutedTimeBenchmarkAvgSamples util.Ring `csv:"-"`
 	ExecutedTotal                   int64     `csv:"executed_total"`
+	GpuUtilization                  float64   `csv:"gpu_utilization"`
+	GpuMemory                       float64   `csv:"gpu_memory"`
 	BoltsPredecessor                []string  `csv:"-"`
+	ScaleUpPeriod                   int       `csv:"-"`
+	RebalancePeriod                 int       `csv:"-"`
 }
 
 func (b *Bolt) clearStatsTimeWindow() {
@@ -36,10 +37,10 @@ func (b *Bolt) clearStatsTimeWindow() {
 	b.ExecutedTimeAvg = 0
 }
 
+// GetExecutedTimeAvg returns the mean executed time of the windows in the ring of the bolt, the last planning
+// interval.
 func (b *Bolt) GetExecutedTimeAvg() float64 {
-	v, _ := stats.Mean(b.ExecutedTimeAvgSamples)
-	b.ExecutedTimeAvgSamples = nil
-	return v
+	return b.ExecutedTimeAvgSamples.Mean()
 }
 
 type Spout struct {
@@ -52,34 +53,67 @@ type Topology struct {
 	Benchmark           bool    `csv:"-"`
 	InputRateAccum      int64   `csv:"-"`
 	InputRateT          int64   `csv:"input_rate"`
-	InputRate           []int64 `csv:"-"`
+	InputRateSmooth     float64 `csv:"input_rate_smooth"`
+	InputRate           History `csv:"-"`
 	PredictedInputRate  []int64 `csv:"-"`
 	PredictModel        string  `csv:"predict_model"`
 	PredictedInputRateT int64   `csv:"predicted_input_rate"`
 	Latency             float64 `csv:"latency"`
+	Profile             string  `csv:"profile"`
+	State               string  `csv:"state"`
+	Cost                float64 `csv:"cost"`
+	Lag                 int64   `csv:"lag"`
+	Throughput          int64   `csv:"throughput"`
+	Degradation         float64 `csv:"degradation"`
+	SlaScore            float64 `csv:"sla_score"`
+	Acked               int64   `csv:"acked"`
+	AckedAccum          int64   `csv:"-"`
+	EmittedAccum        int64   `csv:"-"`
 	Bolts               []Bolt  `csv:"-"`
 	Spouts              []Spout `csv:"-"`
 }
 
+// Clone returns a copy of the topology that shares no buffer with it: the bolts and their rings, the spouts, and
+// the input rates, actual and predicted. It is published to other goroutines while the topology keeps changing.
+func (t *Topology) Clone() Topology {
+	clone := *t
+	clone.InputRate = t.InputRate.Clone()
+	clone.PredictedInputRate = append([]int64(nil), t.PredictedInputRate...)
+	clone.Spouts = append([]Spout(nil), t.Spouts...)
+	clone.Bolts = make([]Bolt, len(t.Bolts))
+	for i, bolt := range t.Bolts {
+		bolt.ExecutedTimeAvgSamples = t.Bolts[i].ExecutedTimeAvgSamples.Clone()
+		bolt.ExecutedTimeBenchmarkAvgSamples = t.Bolts[i].ExecutedTimeBenchmarkAvgSamples.Clone()
+		bolt.BoltsPredecessor = append([]string(nil), bolt.BoltsPredecessor...)
+		clone.Bolts[i] = bolt
+	}
+	return clone
+}
+
 func (t *Topology) Init(id string) {
 	t.Id = id
+	t.InputRate = NewHistory(viper.GetInt("storm.adaptive.prediction_samples"))
 	t.PredictedInputRate = make([]int64, viper.GetInt("storm.adaptive.analyze_samples"))
 }
 
-func (t *Topology) CreateTopology(summaryTopology SummaryTopology) {
+func (t *Topology) CreateTopology(cluster Cluster, summaryTopology SummaryTopology) {
 	// Add Bolts
 	for _, boltCurrent := range summaryTopology.Bolts {
 		if !strings.Contains(boltCurrent.BoltID, "__") {
 			var bolt = Bolt{
 				Name:     boltCurrent.BoltID,
-				Replicas: 1,
+				Replicas: util.GetBoltConfig(boltCurrent.BoltID).MinReplicas,
+				// The executed time is averaged over the windows since the last planning, the last planning_samples,
+				// and the benchmark takes the first benchmark_samples windows
+				ExecutedTimeAvgSamples:          util.NewRing(viper.GetInt("storm.adaptive.planning_samples")),
+				ExecutedTimeBenchmarkAvgSamples: util.NewRing(viper.GetInt("storm.adaptive.benchmark_samples")),
 			}
 			// Add bolts predecessor of current Bolt
-			boltMetrics := GetComponentBolt(summaryTopology.Id, bolt.Name)
+			boltMetrics := cluster.GetComponentBolt(summaryTopology.Id, bolt.Name)
 			// Waiting for the topology execution
 			for len(boltMetrics.InputStats) == 0 {
 				time.Sleep(200 * time.Millisecond)
-				boltMetrics = GetComponentBolt(summaryTopology.Id, bolt.Name)
+				boltMetrics = cluster.GetComponentBolt(summaryTopology.Id, bolt.Name)
 			}
 			for i := range boltMetrics.InputStats {
 				bolt.BoltsPredecessor = append(bolt.BoltsPredecessor, boltMetrics.InputStats[i].Component)
@@ -97,6 +131,10 @@ func (t *Topology) CreateTopology(summaryTopology SummaryTopology) {
 		t.Spouts = append(t.Spouts, spout)
 	}
 
+	if !viper.GetBool("features.exporters") {
+		return
+	}
+
 	if err := util.CreateDir(t.Id); err != nil {
 		fmt.Printf("error mkdir: %v\n", err)
 	}
@@ -112,10 +150,33 @@ func (t *Topology) CreateTopology(summaryTopology SummaryTopology) {
 	}
 }
 
-func (t *Topology) InitReplicas() {
+// Sinks returns the terminal bolts of the topology, those that are not the predecessor of any bolt.
+func (t *Topology) Sinks() []string {
+	var sinks []string
+	for _, bolt := range t.Bolts {
+		sink := true
+		for _, other := range t.Bolts {
+			for _, predecessor := range other.BoltsPredecessor {
+				if predecessor == bolt.Name {
+					sink = false
+				}
+			}
+		}
+		if sink {
+			sinks = append(sinks, bolt.Name)
+		}
+	}
+	return sinks
+}
+
+func (t *Topology) InitReplicas(cluster Cluster) {
 	for _, bolt := range t.Bolts {
-		if errRedis := util.RedisSet(bolt.Name, strconv.FormatInt(1, 10)); errRedis != nil {
-			log.Printf("init replicas error: %v\n", errRedis)
+		if viper.GetBool("dry_run") {
+			log.Printf("dry-run: init replicas bolt={%s},replicas={%d}\n", bolt.Name, bolt.Replicas)
+			continue
+		}
+		if err := cluster.SetReplicas(bolt.Name, bolt.Replicas); err != nil {
+			log.Printf("init replicas error: %v\n", err)
 		}
 	}
 }
@@ -136,24 +197,18 @@ func (t *Topology) BenchmarkExecutedTimeAvg() {
 	t.Benchmark = true
 
 	for i := range t.Bolts {
-		var samples []float64
-		for j := range t.Bolts[i].ExecutedTimeBenchmarkAvgSamples {
-			if !math.IsNaN(t.Bolts[i].ExecutedTimeBenchmarkAvgSamples[j]) {
-				samples = append(samples, t.Bolts[i].ExecutedTimeBenchmarkAvgSamples[j])
-			}
-		}
-
-		var normSamples []float64
-		meanSamples, _ := stats.Mean(samples)
-		stdDevSamples, _ := stats.StandardDeviation(samples)
-		upperLimit := meanSamples + stdDevSamples
-		lowerLimit := meanSamples - stdDevSamples
-		for j := range samples {
-			if lowerLimit <= samples[j] && samples[j] <= upperLimit {
-				normSamples = append(normSamples, samples[j])
+		samples := &t.Bolts[i].ExecutedTimeBenchmarkAvgSamples
+		all := samples.Stats()
+		upperLimit := all.Mean() + all.StdDev()
+		lowerLimit := all.Mean() - all.StdDev()
+
+		var normSamples util.Welford
+		for j := 0; j < samples.Len(); j++ {
+			if sample := samples.At(j); lowerLimit <= sample && sample <= upperLimit {
+				normSamples.Add(sample)
 			}
 		}
 
-		t.Bolts[i].ExecutedTimeBenchmarkAvg, _ = stats.Mean(normSamples)
+		t.Bolts[i].ExecutedTimeBenchmarkAvg = normSamples.Mean()
 	}
 }
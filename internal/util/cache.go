@@ -5,6 +5,7 @@ import (
 	"github.com/go-redis/redis/v8"
 	"github.com/spf13/viper"
 	"log"
+	"time"
 )
 
 func RedisFlush() (string, error) {
@@ -46,3 +47,56 @@ func RedisSet(key, value string) error {
 		return nil
 	}
 }
+
+// RedisSetNX sets the key only when it does not exist, expiring after ttl, and reports whether it was set.
+func RedisSetNX(key, value string, ttl time.Duration) (bool, error) {
+	host := viper.GetString("redis.host")
+	port := viper.GetString("redis.port")
+	addr := host + ":" + port
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: addr,
+	})
+	defer rdb.Close()
+
+	return rdb.SetNX(context.Background(), key, value, ttl).Result()
+}
+
+// RedisSetTTL sets the key, expiring after ttl.
+func RedisSetTTL(key, value string, ttl time.Duration) error {
+	host := viper.GetString("redis.host")
+	port := viper.GetString("redis.port")
+	addr := host + ":" + port
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: addr,
+	})
+	defer rdb.Close()
+
+	return rdb.Set(context.Background(), key, value, ttl).Err()
+}
+
+// RedisScan returns the values of the keys that match the pattern, by key.
+func RedisScan(pattern string) (map[string]string, error) {
+	host := viper.GetString("redis.host")
+	port := viper.GetString("redis.port")
+	addr := host + ":" + port
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: addr,
+	})
+	defer rdb.Close()
+
+	ctx := context.Background()
+	values := make(map[string]string)
+	iter := rdb.Scan(ctx, 0, pattern, 0).Iterator()
+	for iter.Next(ctx) {
+		// the key may expire between the scan and the get
+		if value, err := rdb.Get(ctx, iter.Val()).Result(); err == nil {
+			values[iter.Val()] = value
+		} else if err != redis.Nil {
+			return values, err
+		}
+	}
+	return values, iter.Err()
+}
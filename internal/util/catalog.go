@@ -0,0 +1,60 @@
+package util
+
+import (
+	"fmt"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// CatalogModel is a model of the catalog of predictor.catalog. The type is the protocol of its endpoint: only
+// predictor_api, the POST of the Predictor API (py/app.py), is known. The resources are hints of what the model
+// needs to run, recorded for the operator and not enforced.
+type CatalogModel struct {
+	Name      string            `mapstructure:"name"`
+	Type      string            `mapstructure:"type"`
+	Endpoint  string            `mapstructure:"endpoint"`
+	Horizon   int               `mapstructure:"horizon"`
+	Resources map[string]string `mapstructure:"resources"`
+}
+
+// ReadCatalog reads the models of the catalog file of predictor.catalog, none when it is not set.
+func ReadCatalog() ([]CatalogModel, error) {
+	filename := viper.GetString("predictor.catalog")
+	if filename == "" {
+		return nil, nil
+	}
+	file := viper.New()
+	file.SetConfigFile(filename)
+	if err := file.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("predictor.catalog: %v", err)
+	}
+
+	var models []CatalogModel
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{WeaklyTypedInput: true, Result: &models})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(file.Get("models")); err != nil {
+		return nil, fmt.Errorf("predictor.catalog %s: %v", filename, err)
+	}
+
+	names := make(map[string]bool)
+	for i := range models {
+		if models[i].Type == "" {
+			models[i].Type = "predictor_api"
+		}
+		switch {
+		case models[i].Name == "":
+			return nil, fmt.Errorf("predictor.catalog %s: models[%d]: name required", filename, i)
+		case names[models[i].Name]:
+			return nil, fmt.Errorf("predictor.catalog %s: model %q listed twice", filename, models[i].Name)
+		case models[i].Type != "predictor_api":
+			return nil, fmt.Errorf("predictor.catalog %s: model %q: unknown type %q", filename, models[i].Name, models[i].Type)
+		case models[i].Endpoint == "":
+			return nil, fmt.Errorf("predictor.catalog %s: model %q: endpoint required", filename, models[i].Name)
+		}
+		names[models[i].Name] = true
+	}
+	return models, nil
+}
@@ -0,0 +1,60 @@
+package util
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time of the adaptive system. The real clock follows the wall clock, and the logical clock
+// only moves when it is advanced, so a simulation gives the same result no matter when or how fast it runs.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type RealClock struct{}
+
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+func (RealClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// LogicalClock starts at a fixed time, and Sleep advances it without waiting.
+type LogicalClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewLogicalClock(start time.Time) *LogicalClock {
+	return &LogicalClock{now: start}
+}
+
+func (c *LogicalClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *LogicalClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+var clock Clock = RealClock{}
+
+// SetClock replaces the clock of the adaptive system, the real one by default.
+func SetClock(c Clock) {
+	clock = c
+}
+
+func Now() time.Time {
+	return clock.Now()
+}
+
+func Sleep(d time.Duration) {
+	clock.Sleep(d)
+}
@@ -1,15 +1,473 @@
 package util
 
-import "fmt"
-import "github.com/spf13/viper"
+import (
+	"errors"
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+	"log"
+	"strings"
+)
 
-func LoadConfig() error {
-	viper.SetConfigName("config")
-	viper.AddConfigPath("configs")
+// EnvPrefix is the prefix of the environment variables bound to the config keys, e.g. storm.adaptive.limit_replicas
+// is read from SPS_STORM_ADAPTIVE_LIMIT_REPLICAS.
+const EnvPrefix = "SPS"
+
+// Default is a config key with the value used when neither the config file nor the environment sets it, and the
+// comment written for it by `config init`.
+type Default struct {
+	Key     string
+	Value   interface{}
+	Comment string
+}
+
+var Defaults = []Default{
+	{"dry_run", false, "run the whole pipeline but make every actuator (replicas updates) a no-op"},
+	{"features.prediction", true, "query the predictive model (when disabled, the basic model is used)"},
+	{"features.exporters", true, "save the statistics of the topology and the bolts as csv"},
+	{"features.reports", false, "write a JSON report of each time window in reports/ of the statistics of the topology"},
+	{"features.rest_api", true, "run the REST server that receives the metrics of the topology"},
+	{"features.external_metrics", false, "serve the predicted input rate and the pressure of the bolts as Kubernetes external metrics"},
+	{"features.control_api", false, "serve the gRPC control API, for planners that run in another process"},
+	{"features.inspection_api", false, "serve the read-only HTTP inspection API, for dashboards"},
+	{"features.federation", false, "coordinate the planning with the controllers of other clusters through Redis"},
+	{"backend", "storm", "stream processing system managed by the adaptive system: storm, flink, heron, pulsar, spark"},
+	{"metrics_source", "cluster", "source of the metrics of the topology: cluster (the API of the backend), prometheus"},
+	{"nimbus.host", "localhost", "host of the Storm UI (Nimbus) REST API"},
+	{"nimbus.port", 8772, "port of the Storm UI (Nimbus) REST API"},
+	{"nimbus.workers", 8, "concurrent requests that poll the metrics of the components"},
+	{"nimbus.timeout", 5000, "timeout (ms) of each request of the metrics of a component"},
+	{"nimbus.max_failed_components", 0, "components whose metrics may fail in a window before the window fails"},
+	{"flink.host", "localhost", "host of the REST API of the Flink JobManager"},
+	{"flink.port", 8081, "port of the REST API of the Flink JobManager"},
+	{"flink.job_id", "", "id of the managed Flink job (empty for the first running job)"},
+	{"heron.tracker_host", "localhost", "host of the Heron Tracker"},
+	{"heron.tracker_port", 8888, "port of the Heron Tracker"},
+	{"heron.cluster", "local", "Heron cluster of the topology"},
+	{"heron.role", "", "role of the topology, passed to heron update with the environ when set"},
+	{"heron.environ", "default", "environ of the topology"},
+	{"heron.topology", "", "name of the managed topology (empty for the first topology of the cluster)"},
+	{"heron.cli", "heron", "path of the heron command"},
+	{"pulsar.admin_url", "http://localhost:8080", "URL of the admin REST API of Pulsar"},
+	{"pulsar.tenant", "public", "tenant of the functions"},
+	{"pulsar.namespace", "default", "namespace of the functions"},
+	{"pulsar.functions", []string{}, "functions of the pipeline, chained by their input and output topics"},
+	{"prometheus.url", "http://localhost:9090", "URL of Prometheus, when metrics_source is prometheus"},
+	{"prometheus.queries.spout_emitted", `sum(storm_topology_emitted_total{topology_id="{{.Topology}}",component_id="{{.Component}}"})`, "PromQL template of the tuples emitted by a spout (counter)"},
+	{"prometheus.queries.emitted", `sum(storm_topology_emitted_total{topology_id="{{.Topology}}",component_id="{{.Component}}",stream_id="{{.Stream}}"})`, "PromQL template of the tuples emitted by a component to a stream (counter)"},
+	{"prometheus.queries.executed", `sum(storm_topology_executed_total{topology_id="{{.Topology}}",component_id="{{.Component}}"})`, "PromQL template of the tuples executed by a bolt (counter)"},
+	{"prometheus.queries.execute_latency", `avg(storm_topology_execute_latency_ms{topology_id="{{.Topology}}",component_id="{{.Component}}"})`, "PromQL template of the execute latency (ms) of a bolt"},
+	{"prometheus.queries.latency", "", "PromQL template of the latency (ms) of the topology (empty uses the REST server)"},
+	{"prometheus.queries.gpu_utilization", "", "PromQL template of the GPU utilization (0-100) of a bolt (empty for none)"},
+	{"prometheus.queries.gpu_memory", "", "PromQL template of the GPU memory (MiB) used by a bolt (empty for none)"},
+	{"redis.host", "localhost", "host of the Redis cache where the replicas of each bolt are written"},
+	{"redis.port", 6379, "port of the Redis cache"},
+	{"predictor.host", "localhost", "host of the Predictor API (py/app.py)"},
+	{"predictor.port", 5000, "port of the Predictor API"},
+	{"predictor.workers", 4, "concurrent requests that query the models of the ensemble"},
+	{"predictor.timeout", 10000, "timeout (ms) for all the models of the ensemble to answer"},
+	{"predictor.catalog", "", "YAML file of the models of the catalog, with their endpoints (empty for the models of the Predictor API)"},
+	{"predictor.max_model_age", 0.0, "hours since the training reported by a model before it is flagged stale (0 disables it)"},
+	{"predictor.exclude_stale", false, "leave the stale models out of the mean of the ensemble, while a fresh model answers"},
+	{"storm.deploy.duration", 22, "time of the experiment (minutes)"},
+	{"storm.deploy.script", "testingApp.sh", "app script (in the scripts folder) that deploys the Storm application"},
+	{"storm.deploy.dataset", "constant2", "dataset passed to the app script"},
+	{"storm.deploy.analyze", true, "adapt (or not) the Storm application"},
+	{"storm.adaptive.time_window_size", 1, "size of the monitor time window (seconds) where a sample is obtained"},
+	{"storm.adaptive.benchmark_samples", 60, "number of samples used by the benchmark of the executed time"},
+	{"storm.adaptive.analyze_samples", 15, "analyze module time window (samples)"},
+	{"storm.adaptive.predictive_model", "basic", "model used by input prediction: basic, linear_regression, fft, ann, random_forest, svm, ridge, bayesian, gaussian, sgd, or a model of predictor.catalog"},
+	{"storm.adaptive.prediction_samples", 30, "number of samples used by the predictive model"},
+	{"storm.adaptive.prediction_number", 15, "number of predictions made by the predictive model"},
+	{"storm.adaptive.ensemble", []string{}, "models of the Predictor API queried together, whose mean is the prediction (empty for predictive_model alone)"},
+	{"storm.adaptive.planning_samples", 5, "plan module time window (samples)"},
+	{"storm.adaptive.limit_replicas", 25, "limit of number of pool replicas"},
+	{"storm.adaptive.gpu_slots", 0, "GPUs available to the bolts with gpus, which scale up within them (0 unlimited)"},
+	{"storm.adaptive.preset", "", "preset of the adaptive parameters: conservative, balanced, aggressive (empty for none)"},
+	{"storm.adaptive.headroom", 0.0, "fraction of extra capacity planned over the predicted load"},
+	{"storm.adaptive.scale_down_cooldown", 0, "planning windows that a bolt waits after scaling up before it can scale down"},
+	{"storm.adaptive.rebalance_blackout", 0, "windows after a rebalance of a bolt whose executed time is left out of its samples"},
+	{"storm.adaptive.watch.interval", 0, "interval (ms) of the polls of the status of the topology after a rebalance, until it completes (0 disables it)"},
+	{"storm.adaptive.watch.timeout", 60000, "time (ms) after which a rebalance that has not completed is no longer watched"},
+	{"storm.adaptive.decisions_limit", 10000, "decisions of the planning kept in memory, for the snapshots and the golden run (the oldest are forgotten)"},
+	{"storm.adaptive.degraded_threshold", 0.0, "degradation above which the topology is degraded and its bolts do not scale down (0 disables it)"},
+	{"storm.adaptive.safe_mode.degraded_windows", 0, "consecutive degraded windows that enter the safe mode (0 disables it)"},
+	{"storm.adaptive.safe_mode.failed_rebalances", 0, "consecutive failed rebalances that enter the safe mode (0 disables it)"},
+	{"storm.adaptive.safe_mode.alert_url", "", "URL that receives a POST when the safe mode is entered (empty for none)"},
+	{"storm.adaptive.latency_source", "rest", "source of the latency of the topology: rest (latency received by the REST server, or metrics_source), complete (complete latency of the tuples acked by the spouts, end to end)"},
+	{"storm.adaptive.sla_latency", 1000.0, "latency (ms) of the SLA of the topology, a window above it is a violation (0 disables it)"},
+	{"storm.adaptive.sla_lag", 0, "consumer lag (tuples) of the SLA of the topology, for the SLA score (0 leaves the lag out)"},
+	{"storm.adaptive.sla_score.weights.latency", 1.0, "weight of the latency in the SLA score"},
+	{"storm.adaptive.sla_score.weights.degradation", 1.0, "weight of the degradation in the SLA score"},
+	{"storm.adaptive.sla_score.weights.lag", 1.0, "weight of the lag in the SLA score"},
+	{"storm.adaptive.sla_score.floors.latency", 0.0, "compliance of the latency below which the SLA score is 0"},
+	{"storm.adaptive.sla_score.floors.degradation", 0.0, "compliance of the degradation below which the SLA score is 0"},
+	{"storm.adaptive.sla_score.floors.lag", 0.0, "compliance of the lag below which the SLA score is 0"},
+	{"storm.adaptive.pipeline", false, "run the analysis in its own stage, so a slow predictor does not delay the monitor"},
+	{"storm.adaptive.planner", "internal", "planner of the replicas: internal, or external (submitted through the control API)"},
+	{"storm.adaptive.input_rate_source", "spout", "source of the input rate of the topology: spout (tuples emitted by the spouts), kafka (messages produced in kafka.topics), mqtt (messages received by mqtt.broker), nats (messages stored in nats.stream), http (count read from http_counter.url)"},
+	{"storm.adaptive.input_rate_smoothing", 0.0, "factor (0-1) of the EWMA of the input rate used by the prediction, the planning and the degradation (0 disables it)"},
+	{"storm.adaptive.output_bolts", []string{}, "bolts whose executed tuples are the throughput of the topology (empty for the sink bolts of the DAG)"},
+	{"storm.adaptive.degradation", "output", "degradation of the throughput: output (input rate not executed by the output bolts) or acked (tuples emitted by the spouts and not acked)"},
+	{"storm.adaptive.baseline_replicas", 0, "replicas of each bolt in a static provisioning (0 is the min_replicas of the bolt)"},
+	{"storm.adaptive.baseline", "config", "baseline replicas of the bolts: config (baseline_replicas), initial (the replicas deployed when first adapted) or max (the most replicas planned)"},
+	{"storm.adaptive.baseline_file", "baseline.json", "file where the initial and max baselines are kept across runs"},
+	{"storm.shutdown.revert_replicas", false, "revert the bolts to the baseline replicas when the system stops"},
+	{"storm.rest_metric.port", 3000, "port of the REST server that receives the latency of the topology"},
+	{"storm.csv", "stats/", "folder where the statistics are saved"},
+	{"control_api.port", 50051, "port of the gRPC control API"},
+	{"control_api.token", "", "static bearer token of the operators of the control API (empty for none)"},
+	{"control_api.viewer_token", "", "static bearer token of the viewers of the control API, who only subscribe to the snapshots (empty for none)"},
+	{"control_api.token_file", "", "file with the static bearer token, instead of control_api.token"},
+	{"control_api.collector_buffer", 64, "time windows a collector of StreamMetrics may fall behind before it is disconnected"},
+	{"control_api.rate_limit.per_minute", 12.0, "calls per minute of each client to the methods of the operators of the control API (0 disables the limit)"},
+	{"control_api.rate_limit.burst", 4.0, "calls at once of each client to the methods of the operators of the control API"},
+	{"control_api.oidc.issuer", "", "OIDC issuer of the JWT bearer tokens accepted by the control API (empty for none)"},
+	{"control_api.oidc.audience", "", "audience required in the OIDC tokens (empty accepts any)"},
+	{"control_api.oidc.roles_claim", "roles", "claim of the OIDC tokens with their roles: operator, or else viewer"},
+	{"control_api.oidc.leeway", 60, "clock skew allowed on the exp, nbf and iat of the OIDC tokens (seconds)"},
+	{"inspection_api.port", 8090, "port of the HTTP inspection API"},
+	{"federation.member", "", "name of this controller in the federation (empty for the id of the topology)"},
+	{"federation.prefix", "federation.", "prefix of the Redis keys shared by the members of the federation"},
+	{"federation.replica_budget", 0, "replicas of the bolts of all the members together (0 unlimited)"},
+	{"federation.stagger", 0, "time (ms) between the rebalances of two members (0 disables it)"},
+	{"federation.ttl", 60000, "time (ms) the replicas of a member count in the budget without an update"},
+	{"kubernetes.metrics_port", 6443, "port of the external metrics API"},
+	{"kubernetes.tls_cert", "", "TLS certificate of the external metrics API (empty serves plain HTTP)"},
+	{"kubernetes.tls_key", "", "TLS key of the external metrics API"},
+	{"kubernetes.api_server", "", "URL of the Kubernetes API (empty uses the service account of the pod)"},
+	{"kubernetes.token_file", "", "bearer token of the Kubernetes API (empty uses the service account of the pod)"},
+	{"kubernetes.insecure", false, "skip the verification of the TLS certificate of kubernetes.api_server"},
+	{"kubernetes.workers.enabled", false, "scale the Deployment or StatefulSet of the Storm workers with the replicas of the bolts"},
+	{"kubernetes.workers.namespace", "storm", "namespace of the Storm workers"},
+	{"kubernetes.workers.kind", "statefulsets", "kind of the Storm workers: statefulsets or deployments"},
+	{"kubernetes.workers.name", "storm-supervisor", "name of the StatefulSet or Deployment of the Storm workers"},
+	{"kubernetes.workers.executors_per_worker", 4, "executors (replicas of the bolts) that fit in a worker pod"},
+	{"kubernetes.workers.min_workers", 1, "minimum number of worker pods"},
+	{"kubernetes.workers.max_workers", 10, "maximum number of worker pods"},
+	{"spark.ui_url", "http://localhost:4040", "URL of the UI of the Spark driver, with its REST API and metrics servlet"},
+	{"spark.app_id", "", "id of the Spark application (empty for the first application of the driver)"},
+	{"spark.query", "", "name of the streaming query (empty for the first query with metrics)"},
+	{"spark.source", "source", "name of the spout that stands for the source of the query"},
+	{"spark.redis_prefix", "spark.", "prefix of the Redis keys read by the listener of the driver"},
+	{"spark.partitions_per_executor", 2, "shuffle partitions signaled per executor"},
+	{"kafka.brokers", []string{"localhost:9092"}, "brokers of the Kafka cluster"},
+	{"kafka.topics", []string{}, "source topics of the topology, for the kafka input rate source"},
+	{"kafka.timeout", 2000, "timeout (ms) of the requests to Kafka"},
+	{"mqtt.broker", "tcp://localhost:1883", "URL of the MQTT broker"},
+	{"mqtt.client_id", "sps-storm", "client id of the adaptive system in the broker"},
+	{"mqtt.username", "", "username of the broker (empty for none)"},
+	{"mqtt.password", "", "password of the broker"},
+	{"mqtt.counter", "sys", "count of the messages: sys (counter of the broker in mqtt.sys_topic) or topics (messages published in mqtt.topics)"},
+	{"mqtt.sys_topic", "$SYS/broker/publish/messages/received", "topic where the broker publishes the cumulative count of the messages received"},
+	{"mqtt.topics", []string{}, "topic filters of the devices, for the topics counter"},
+	{"mqtt.timeout", 2000, "timeout (ms) of the connection and the subscription"},
+	{"http_counter.url", "", "URL whose body is the count of the tuples sent to the topology, for the http input rate source"},
+	{"http_counter.timeout", 2000, "timeout (ms) of the requests to http_counter.url"},
+	{"nats.monitor_url", "http://localhost:8222", "URL of the monitoring server of NATS"},
+	{"nats.account", "", "account of the stream (empty for any account)"},
+	{"nats.stream", "", "JetStream stream in front of the spouts"},
+	{"nats.consumer", "", "consumer of the spouts in nats.stream, whose lag is monitored (empty disables it)"},
+	{"nats.plan_lag", true, "add the consumer lag to the queue of the bolts fed by the spouts when planning"},
+	{"nats.timeout", 2000, "timeout (ms) of the requests to the monitoring server"},
+	{"cost.provider", "static", "source of the prices: static (cost.replica_hour), aws (Price List API) or gcp (Cloud Billing Catalog API)"},
+	{"cost.currency", "USD", "currency of the prices"},
+	{"cost.replica_hour", 0.0, "price of a replica of a bolt during an hour, for the static provider"},
+	{"cost.replicas_per_instance", 4, "replicas of the bolts that fit in an instance, for the aws and gcp providers"},
+	{"cost.aws.instance_type", "m5.large", "instance type of the workers"},
+	{"cost.aws.region", "us-east-1", "region of the workers"},
+	{"cost.gcp.api_key", "", "API key of the Cloud Billing Catalog API"},
+	{"cost.gcp.service", "6F81-5844-456A", "id of the service of the sku (Compute Engine)"},
+	{"cost.gcp.sku", "", "id of the sku of an instance of the workers, priced by hour"},
+	{"chaos.enabled", false, "inject noise, dropouts and delays in the metrics of the cluster"},
+	{"chaos.noise", 0.05, "standard deviation of the relative gaussian noise added to each metric"},
+	{"chaos.dropout", 0.0, "probability of dropping a response of the metrics API"},
+	{"chaos.restart", 0.0, "probability of a restart of the workers in a window, which resets the counters of the metrics"},
+	{"chaos.delay", 0, "maximum delay (ms) added to each response of the cluster"},
+	{"chaos.seed", 1, "seed of the random numbers of the chaos layer"},
+	{"simulator.trace", "", "input-rate trace of the simulation: one rate per line, or a csv with an input_rate column"},
+	{"simulator.spout", "spout", "name of the simulated spout"},
+	{"simulator.start", "2024-01-01T00:00:00Z", "time (RFC 3339) of the logical clock at the start of the simulation"},
+	{"workload.kind", "sinusoidal", "kind of synthetic trace: constant, sinusoidal, step, poisson, flash_crowd, seasonal"},
+	{"workload.length", 600, "number of time windows of the trace"},
+	{"workload.base", 1000.0, "base rate (tuples per window), the mean of poisson"},
+	{"workload.amplitude", 500.0, "amplitude of sinusoidal and seasonal, size of the step of step"},
+	{"workload.period", 120.0, "period (windows) of sinusoidal and seasonal"},
+	{"workload.step_at", 300, "window where the step of step occurs"},
+	{"workload.trend", 0.0, "rate added per window by seasonal"},
+	{"workload.noise", 0.0, "standard deviation of the gaussian noise, as a fraction of the rate"},
+	{"workload.burst_probability", 0.01, "probability per window that a burst of flash_crowd starts"},
+	{"workload.burst_duration", 10, "windows of a burst of flash_crowd"},
+	{"workload.burst_factor", 3.0, "rate of a burst of flash_crowd, as a multiple of base"},
+	{"workload.seed", 1, "seed of the random numbers of the trace"},
+}
+
+// BoltDefaults are the per-bolt overrides of the adaptive parameters, set in storm.bolts.<bolt>.
+var BoltDefaults = []Default{
+	{"min_replicas", 1, "minimum number of replicas of the bolt"},
+	{"max_replicas", 0, "maximum number of replicas of the bolt (0 is storm.adaptive.limit_replicas)"},
+	{"sla_utilization", 1.0, "maximum utilization planned for each replica of the bolt, between 0 and 1"},
+	{"scaling_step", 0, "maximum change of replicas of the bolt in one plan (0 is unlimited)"},
+	{"exclude", false, "exclude the bolt from the adaptation, keeping min_replicas"},
+	{"gpus", 0, "GPUs of each replica of the bolt, counted in storm.adaptive.gpu_slots (0 for a bolt without GPU)"},
+}
+
+// BoltConfig is the configuration of a bolt, with its overrides applied.
+type BoltConfig struct {
+	MinReplicas    int64   `mapstructure:"min_replicas"`
+	MaxReplicas    int64   `mapstructure:"max_replicas"`
+	SlaUtilization float64 `mapstructure:"sla_utilization"`
+	ScalingStep    int64   `mapstructure:"scaling_step"`
+	Exclude        bool    `mapstructure:"exclude"`
+	Gpus           int64   `mapstructure:"gpus"`
+}
+
+// UnmarshalSection decodes a config key into out. Unlike viper.UnmarshalKey, the flags and the environment
+// variables of the nested keys of a section are applied.
+func UnmarshalSection(key string, out interface{}) error {
+	var value interface{}
+	key = strings.ToLower(key)
+	prefix := key + "."
+	for _, k := range viper.AllKeys() {
+		if k == key {
+			value = viper.Get(k)
+			break
+		}
+		if strings.HasPrefix(k, prefix) {
+			if value == nil {
+				value = make(map[string]interface{})
+			}
+			section := value.(map[string]interface{})
+			path := strings.Split(strings.TrimPrefix(k, prefix), ".")
+			for _, p := range path[:len(path)-1] {
+				if _, ok := section[p].(map[string]interface{}); !ok {
+					section[p] = make(map[string]interface{})
+				}
+				section = section[p].(map[string]interface{})
+			}
+			section[path[len(path)-1]] = viper.Get(k)
+		}
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		WeaklyTypedInput: true,
+		Result:           out,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(value)
+}
+
+func GetBoltConfig(bolt string) BoltConfig {
+	var config BoltConfig
+	if err := UnmarshalSection("storm.bolts."+bolt, &config); err != nil {
+		log.Printf("config: bolt={%s},error={%v}\n", bolt, err)
+	}
+
+	limitReplicas := viper.GetInt64("storm.adaptive.limit_replicas")
+	if config.MinReplicas < 1 {
+		config.MinReplicas = 1
+	}
+	if config.MaxReplicas < 1 || config.MaxReplicas > limitReplicas {
+		config.MaxReplicas = limitReplicas
+	}
+	if config.MinReplicas > config.MaxReplicas {
+		config.MinReplicas = config.MaxReplicas
+	}
+	if config.SlaUtilization <= 0 || config.SlaUtilization > 1 {
+		config.SlaUtilization = 1
+	}
+	return config
+}
+
+// SimulatorBoltDefaults are the keys of each entry of simulator.bolts, listed in topological order.
+var SimulatorBoltDefaults = []Default{
+	{"name", "", "name of the bolt"},
+	{"execute_latency", 1.0, "execution time of a tuple (ms)"},
+	{"selectivity", 1.0, "tuples emitted per executed tuple"},
+	{"predecessors", []string{}, "spout or bolts that emit to the bolt"},
+}
+
+// Sections are the comments written by `config init` above each section of the config file.
+var Sections = map[string]string{
+	"features":                 "subsystems enabled in this run",
+	"nimbus":                   "Nimbus component in Storm",
+	"flink":                    "Apache Flink, when backend is flink",
+	"heron":                    "Apache Heron, when backend is heron",
+	"pulsar":                   "Apache Pulsar Functions, when backend is pulsar",
+	"spark":                    "Spark Structured Streaming, when backend is spark",
+	"prometheus":               "Prometheus, when metrics_source is prometheus",
+	"prometheus.queries":       "PromQL templates of the metrics, with {{.Topology}}, {{.Component}} and {{.Stream}}",
+	"redis":                    "Redis cache",
+	"predictor":                "Predictor API",
+	"storm":                    "Apache Storm",
+	"storm.deploy":             "application deployment",
+	"storm.adaptive":           "self-adaptive system",
+	"storm.rest_metric":        "REST app used to obtain the stats of the topology",
+	"storm.adaptive.schedules": "time-of-day profiles of the adaptive parameters",
+	"storm.adaptive.watch":     "completion of the rebalances, to anchor the blackout to it instead of to the plan",
+	"storm.adaptive.sla_score": "compliance of each window with the SLA, from 0 to 1",
+	"storm.adaptive.safe_mode": "baseline replicas and no adaptation after repeated failures, until resumed",
+	"storm.shutdown":           "graceful shutdown, at the end of the experiment or on SIGINT/SIGTERM",
+	"kafka":                    "Apache Kafka, when storm.adaptive.input_rate_source is kafka",
+	"control_api":              "gRPC control API, when features.control_api is enabled",
+	"control_api.oidc":         "OIDC bearer tokens of the control API",
+	"inspection_api":           "HTTP inspection API, when features.inspection_api is enabled",
+	"federation":               "controllers of other clusters sharing a replica budget, when features.federation is enabled",
+	"control_api.rate_limit":   "rate of the calls of each client that act on the topology",
+	"mqtt":                     "MQTT broker of the devices, when storm.adaptive.input_rate_source is mqtt",
+	"http_counter":             "HTTP endpoint of the producers, when storm.adaptive.input_rate_source is http",
+	"nats":                     "NATS JetStream in front of the spouts, for the nats input rate source and the consumer lag",
+	"kubernetes":               "Kubernetes integration",
+	"kubernetes.workers":       "scaling of the worker pods of Storm",
+	"cost":                     "price of the replicas, to report the cost of each window in currency",
+	"chaos":                    "fault injection in the metrics of the cluster, to evaluate the robustness of the adaptive system",
+	"simulator":                "trace-driven simulation of the topology, used by the simulate command",
+	"simulator.bolts":          "bolts of the simulated topology",
+	"workload":                 "synthetic input-rate trace, used by the simulator when simulator.trace is empty and by the workload command",
+	"storm.bolts":              "per-bolt overrides of the adaptive parameters, keyed by bolt name",
+}
+
+// Presets are named combinations of the adaptive parameters, selected with storm.adaptive.preset. A preset
+// replaces the defaults of its keys, so any key set in the config file or the environment still wins.
+var Presets = map[string]map[string]interface{}{
+	"conservative": {
+		"storm.adaptive.headroom":            0.3,
+		"storm.adaptive.scale_down_cooldown": 3,
+		"storm.adaptive.planning_samples":    10,
+		"storm.adaptive.prediction_samples":  60,
+	},
+	"balanced": {
+		"storm.adaptive.headroom":            0.1,
+		"storm.adaptive.scale_down_cooldown": 1,
+		"storm.adaptive.planning_samples":    5,
+		"storm.adaptive.prediction_samples":  30,
+	},
+	"aggressive": {
+		"storm.adaptive.headroom":            0.0,
+		"storm.adaptive.scale_down_cooldown": 0,
+		"storm.adaptive.planning_samples":    2,
+		"storm.adaptive.prediction_samples":  15,
+	},
+}
+
+// EnvName returns the environment variable bound to a config key.
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+func LoadConfig(configFile string) error {
+	for _, d := range Defaults {
+		viper.SetDefault(d.Key, d.Value)
+	}
+
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+
+	if configFile != "" {
+		viper.SetConfigFile(configFile)
+	} else {
+		viper.SetConfigName("config")
+		viper.AddConfigPath("configs")
+	}
 
 	if err := viper.ReadInConfig(); err != nil {
-		return fmt.Errorf("Fatal error config file: %s \n", err)
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return fmt.Errorf("Fatal error config file: %s \n", err)
+		}
+		log.Printf("config: no config file found, using defaults and environment\n")
+	} else if errs := ValidateConfigFile(); len(errs) > 0 {
+		return fmt.Errorf("config %s: %w", viper.ConfigFileUsed(), errors.Join(errs...))
+	}
+
+	if name := viper.GetString("storm.adaptive.preset"); name != "" {
+		if preset, ok := Presets[name]; !ok {
+			return fmt.Errorf("config: unknown preset %q", name)
+		} else {
+			for key, value := range preset {
+				viper.SetDefault(key, value)
+			}
+			log.Printf("config: preset={%s}\n", name)
+		}
+	}
+
+	// AutomaticEnv only applies to direct lookups, so every known key is bound explicitly for Sub,
+	// UnmarshalKey and AllSettings to see the environment too.
+	for _, key := range viper.AllKeys() {
+		if err := viper.BindEnv(key, EnvName(key)); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+// usesModel reports whether the model is storm.adaptive.predictive_model or one of storm.adaptive.ensemble.
+func usesModel(model string) bool {
+	if len(viper.GetStringSlice("storm.adaptive.ensemble")) == 0 {
+		return viper.GetString("storm.adaptive.predictive_model") == model
+	}
+	for _, m := range viper.GetStringSlice("storm.adaptive.ensemble") {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+var predictiveModels = []string{"basic", "linear_regression", "fft", "ann", "random_forest", "svm", "ridge", "bayesian", "gaussian", "sgd"}
+
+func ValidateConfig() []error {
+	var errs []error
+
+	for _, key := range []string{"nimbus.host", "nimbus.port", "redis.host", "redis.port", "storm.deploy.script", "storm.csv"} {
+		if viper.GetString(key) == "" {
+			errs = append(errs, fmt.Errorf("%s: required", key))
+		}
+	}
+
+	for _, key := range []string{"storm.deploy.duration", "storm.adaptive.time_window_size", "storm.adaptive.benchmark_samples", "storm.adaptive.analyze_samples",
+		"storm.adaptive.prediction_samples", "storm.adaptive.prediction_number", "storm.adaptive.planning_samples", "storm.adaptive.limit_replicas"} {
+		if viper.GetInt(key) < 1 {
+			errs = append(errs, fmt.Errorf("%s: must be a positive integer, got %q", key, viper.GetString(key)))
+		}
+	}
+
+	catalog, err := ReadCatalog()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	model := viper.GetString("storm.adaptive.predictive_model")
+	var known bool
+	for _, m := range predictiveModels {
+		if m == model {
+			known = true
+		}
+	}
+	for _, m := range catalog {
+		if m.Name == model {
+			known = true
+		}
+	}
+	if !known {
+		errs = append(errs, fmt.Errorf("storm.adaptive.predictive_model: unknown model %q", model))
+	}
+	// the models of the catalog forecast at most their horizon
+	for _, m := range catalog {
+		if m.Horizon > 0 && m.Horizon < viper.GetInt("storm.adaptive.prediction_number") && usesModel(m.Name) {
+			errs = append(errs, fmt.Errorf("predictor.catalog: model %q forecasts %d periods, prediction_number is %d", m.Name, m.Horizon, viper.GetInt("storm.adaptive.prediction_number")))
+		}
+	}
+
+	if viper.GetFloat64("storm.adaptive.headroom") < 0 {
+		errs = append(errs, fmt.Errorf("storm.adaptive.headroom: must not be negative"))
+	}
+	if viper.GetInt("storm.adaptive.scale_down_cooldown") < 0 {
+		errs = append(errs, fmt.Errorf("storm.adaptive.scale_down_cooldown: must not be negative"))
+	}
+
+	return errs
+}
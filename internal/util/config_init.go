@@ -0,0 +1,171 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WriteDefaultConfig writes a commented config file with the defaults of the code.
+func WriteDefaultConfig(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "# yaml-language-server: $schema=config.schema.json\n# Config\n# Generated from the defaults of sps-storm. Every key can be overridden with the\n# environment variable %s_<KEY>, e.g. %s.\n", EnvPrefix, EnvName("storm.adaptive.limit_replicas")); err != nil {
+		return err
+	}
+
+	var previous []string
+	for _, d := range groupDefaults(Defaults) {
+		path := strings.Split(d.Key, ".")
+		sections := path[:len(path)-1]
+
+		// Open the sections that differ from the previous key
+		common := 0
+		for common < len(sections) && common < len(previous) && sections[common] == previous[common] {
+			common++
+		}
+		for i := common; i < len(sections); i++ {
+			indent := strings.Repeat("  ", i)
+			if i == 0 {
+				if _, err := fmt.Fprintln(w); err != nil {
+					return err
+				}
+			}
+			if comment, ok := Sections[strings.Join(sections[:i+1], ".")]; ok {
+				if _, err := fmt.Fprintf(w, "%s# %s\n", indent, comment); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintf(w, "%s%s:\n", indent, sections[i]); err != nil {
+				return err
+			}
+		}
+		previous = sections
+
+		indent := strings.Repeat("  ", len(sections))
+		if len(sections) == 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if d.Comment != "" {
+			if _, err := fmt.Fprintf(w, "%s# %s\n", indent, d.Comment); err != nil {
+				return err
+			}
+		}
+		// Keys chosen by the presets are left commented out, otherwise they would always override the preset
+		if inPreset(d.Key) {
+			indent += "# "
+		}
+		if _, err := fmt.Fprintf(w, "%s%s: %s\n", indent, path[len(path)-1], yamlValue(d.Value)); err != nil {
+			return err
+		}
+	}
+
+	for _, example := range Examples {
+		if err := writeExample(w, example); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// groupDefaults returns the defaults with the keys of each section together, in the order of the first key of the
+// section, so each section is written once: a key out of its section would open it again, and YAML rejects a
+// mapping key defined twice.
+func groupDefaults(defaults []Default) []Default {
+	order := make(map[string]int)
+	for _, d := range defaults {
+		path := strings.Split(d.Key, ".")
+		for i := 1; i <= len(path); i++ {
+			if _, ok := order[strings.Join(path[:i], ".")]; !ok {
+				order[strings.Join(path[:i], ".")] = len(order)
+			}
+		}
+	}
+	grouped := append([]Default(nil), defaults...)
+	sort.SliceStable(grouped, func(i, j int) bool {
+		a, b := strings.Split(grouped[i].Key, "."), strings.Split(grouped[j].Key, ".")
+		for k := 1; k <= len(a) && k <= len(b); k++ {
+			if prefixA, prefixB := strings.Join(a[:k], "."), strings.Join(b[:k], "."); prefixA != prefixB {
+				return order[prefixA] < order[prefixB]
+			}
+		}
+		return false
+	})
+	return grouped
+}
+
+// Example is a section of the config file written commented out by `config init`, since it has no defaults:
+// either a list of entries or entries keyed by a name.
+type Example struct {
+	Key      string
+	List     bool
+	Defaults []Default
+}
+
+var Examples = []Example{
+	{"storm.adaptive.schedules", true, ScheduleDefaults},
+	{"storm.bolts", false, BoltDefaults},
+	{"simulator.bolts", true, SimulatorBoltDefaults},
+}
+
+func writeExample(w io.Writer, example Example) error {
+	if _, err := fmt.Fprintf(w, "\n# %s (%s)\n", Sections[example.Key], example.Key); err != nil {
+		return err
+	}
+	path := strings.Split(example.Key, ".")
+	for i := range path {
+		if _, err := fmt.Fprintf(w, "# %s%s:\n", strings.Repeat("  ", i), path[i]); err != nil {
+			return err
+		}
+	}
+
+	indent := strings.Repeat("  ", len(path))
+	if !example.List {
+		if _, err := fmt.Fprintf(w, "# %s<name>:\n", indent); err != nil {
+			return err
+		}
+	}
+	for i, d := range example.Defaults {
+		item := "  "
+		if example.List && i == 0 {
+			item = "- "
+		}
+		if _, err := fmt.Fprintf(w, "# %s  # %s\n# %s%s%s: %s\n", indent, d.Comment, indent, item, d.Key, yamlValue(d.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func yamlValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []string:
+		var items []string
+		for _, item := range v {
+			items = append(items, strconv.Quote(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case float64:
+		if s := strconv.FormatFloat(v, 'f', -1, 64); strings.Contains(s, ".") {
+			return s
+		} else {
+			return s + ".0"
+		}
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func inPreset(key string) bool {
+	for _, preset := range Presets {
+		if _, ok := preset[key]; ok {
+			return true
+		}
+	}
+	return false
+}
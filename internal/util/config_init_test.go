@@ -0,0 +1,38 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// The output of `config init` is read back as a config file, with the defaults of the code.
+func TestWriteDefaultConfigRoundTrip(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteDefaultConfig(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(filename)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatalf("read config init: %v", err)
+	}
+	for _, d := range Defaults {
+		if inPreset(d.Key) {
+			continue
+		}
+		if !v.IsSet(d.Key) {
+			t.Errorf("key %s not in config init", d.Key)
+		}
+	}
+}
@@ -70,3 +70,25 @@ func WriteCsv(topologyId string, filename string, data interface{}) error {
 		}
 	}
 }
+
+func ReadCsv(topologyId string, filename string, data interface{}) error {
+	if b, err := os.ReadFile(viper.GetString("storm.csv") + "/" + topologyId + "/" + filename + ".csv"); err != nil {
+		return err
+	} else {
+		return csvutil.Unmarshal(b, data)
+	}
+}
+
+func ListCsv(topologyId string) ([]string, error) {
+	var filenames []string
+	if entries, err := os.ReadDir(viper.GetString("storm.csv") + "/" + topologyId); err != nil {
+		return filenames, err
+	} else {
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".csv") {
+				filenames = append(filenames, strings.TrimSuffix(entry.Name(), ".csv"))
+			}
+		}
+		return filenames, nil
+	}
+}
@@ -0,0 +1,21 @@
+package util
+
+import (
+	"encoding/json"
+	"github.com/spf13/viper"
+	"os"
+)
+
+// WriteJson writes the data as indented JSON in the file of a folder of the statistics of the topology, creating
+// the folder when needed.
+func WriteJson(topologyId string, folder string, filename string, data interface{}) error {
+	dir := viper.GetString("storm.csv") + "/" + topologyId + "/" + folder
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dir+"/"+filename+".json", b, 0644)
+}
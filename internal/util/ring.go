@@ -0,0 +1,87 @@
+package util
+
+import "math"
+
+// Ring is a fixed-capacity buffer of samples, allocated once: when it is full, a new sample replaces the
+// oldest one, so accumulating the samples of each window does not allocate. The mean and the variance are
+// accumulated as the samples come and go, so they are O(1). The zero Ring holds no samples; create it with
+// NewRing.
+type Ring struct {
+	samples []float64
+	start   int
+	n       int
+	stats   Welford // of the samples that are not NaN
+	nan     int     // samples that are NaN
+}
+
+// NewRing returns an empty Ring that holds up to capacity samples (at least one).
+func NewRing(capacity int) Ring {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return Ring{samples: make([]float64, capacity)}
+}
+
+// Push adds a sample, replacing the oldest one when the ring is full.
+func (r *Ring) Push(sample float64) {
+	if r.n == len(r.samples) {
+		r.forget(r.samples[r.start])
+	}
+	if math.IsNaN(sample) {
+		r.nan++
+	} else {
+		r.stats.Add(sample)
+	}
+	if r.n < len(r.samples) {
+		r.samples[(r.start+r.n)%len(r.samples)] = sample
+		r.n++
+	} else {
+		r.samples[r.start] = sample
+		r.start = (r.start + 1) % len(r.samples)
+	}
+}
+
+// Clone returns a copy of the ring that does not share its samples, so it is read while the ring keeps changing.
+func (r *Ring) Clone() Ring {
+	clone := *r
+	clone.samples = append([]float64(nil), r.samples...)
+	return clone
+}
+
+func (r *Ring) Len() int {
+	return r.n
+}
+
+// At returns the i-th sample, from the oldest.
+func (r *Ring) At(i int) float64 {
+	return r.samples[(r.start+i)%len(r.samples)]
+}
+
+func (r *Ring) forget(sample float64) {
+	if math.IsNaN(sample) {
+		r.nan--
+	} else {
+		r.stats.Remove(sample)
+	}
+}
+
+// Mean returns the mean of the samples, NaN when it is empty or holds a NaN sample (as stats.Mean).
+func (r *Ring) Mean() float64 {
+	if r.nan > 0 {
+		return math.NaN()
+	}
+	return r.stats.Mean()
+}
+
+// Variance returns the population variance of the samples, NaN when it is empty or holds a NaN sample.
+func (r *Ring) Variance() float64 {
+	if r.nan > 0 {
+		return math.NaN()
+	}
+	return r.stats.Variance()
+}
+
+// Stats returns the mean and the variance of the samples that are not NaN.
+func (r *Ring) Stats() Welford {
+	return r.stats
+}
@@ -0,0 +1,84 @@
+package util
+
+import (
+	"github.com/spf13/viper"
+	"log"
+	"time"
+)
+
+// ScheduleDefaults are the keys of each entry of storm.adaptive.schedules. The adaptive parameters of an entry
+// are optional, and the ones left out keep the value of storm.adaptive.
+var ScheduleDefaults = []Default{
+	{"name", "", "name of the profile, recorded on each plan"},
+	{"from", 0, "first hour of the day (0-23) of the profile"},
+	{"to", 24, "hour of the day (1-24) where the profile ends, it may be lower than from to wrap around midnight"},
+	{"headroom", 0.0, "headroom during the profile"},
+	{"scale_down_cooldown", 0, "scale_down_cooldown during the profile"},
+	{"max_replicas", 0, "maximum number of replicas of every bolt during the profile (0 keeps the bolt bounds)"},
+	{"baseline_replicas", 0, "baseline replicas of each bolt during the profile (0 keeps storm.adaptive.baseline_replicas)"},
+}
+
+// Schedule is a time-of-day profile of the adaptive parameters.
+type Schedule struct {
+	Name              string   `mapstructure:"name"`
+	From              int      `mapstructure:"from"`
+	To                int      `mapstructure:"to"`
+	Headroom          *float64 `mapstructure:"headroom"`
+	ScaleDownCooldown *int     `mapstructure:"scale_down_cooldown"`
+	MaxReplicas       int64    `mapstructure:"max_replicas"`
+	BaselineReplicas  int64    `mapstructure:"baseline_replicas"`
+}
+
+func (s Schedule) contains(hour int) bool {
+	if s.From <= s.To {
+		return s.From <= hour && hour < s.To
+	}
+	return hour >= s.From || hour < s.To
+}
+
+// Profile is the set of adaptive parameters active at a time of the day.
+type Profile struct {
+	Name              string
+	Headroom          float64
+	ScaleDownCooldown int
+	MaxReplicas       int64
+	BaselineReplicas  int64
+}
+
+// GetProfile returns the adaptive parameters at the given time: the first schedule containing its hour, with
+// storm.adaptive as the fallback for the parameters that the schedule does not set.
+func GetProfile(t time.Time) Profile {
+	profile := Profile{
+		Name:              "default",
+		Headroom:          viper.GetFloat64("storm.adaptive.headroom"),
+		ScaleDownCooldown: viper.GetInt("storm.adaptive.scale_down_cooldown"),
+		BaselineReplicas:  viper.GetInt64("storm.adaptive.baseline_replicas"),
+	}
+
+	var schedules []Schedule
+	if err := UnmarshalSection("storm.adaptive.schedules", &schedules); err != nil {
+		log.Printf("config: schedules error={%v}\n", err)
+		return profile
+	}
+	return profileAt(t, profile, schedules)
+}
+
+func profileAt(t time.Time, profile Profile, schedules []Schedule) Profile {
+	for _, schedule := range schedules {
+		if schedule.contains(t.Hour()) {
+			profile.Name = schedule.Name
+			if schedule.Headroom != nil {
+				profile.Headroom = *schedule.Headroom
+			}
+			if schedule.ScaleDownCooldown != nil {
+				profile.ScaleDownCooldown = *schedule.ScaleDownCooldown
+			}
+			profile.MaxReplicas = schedule.MaxReplicas
+			if schedule.BaselineReplicas > 0 {
+				profile.BaselineReplicas = schedule.BaselineReplicas
+			}
+			break
+		}
+	}
+	return profile
+}
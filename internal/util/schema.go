@@ -0,0 +1,253 @@
+package util
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Schema is the subset of JSON Schema used to describe the config file.
+type Schema struct {
+	Id                   string             `json:"$id,omitempty"`
+	Draft                string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"` // false or *Schema
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+// ConfigSchema returns the JSON Schema of the config file, derived from the defaults of the code.
+func ConfigSchema() *Schema {
+	root := &Schema{
+		Draft:                "http://json-schema.org/draft-07/schema#",
+		Id:                   "config.schema.json",
+		Title:                "sps-storm config",
+		Type:                 "object",
+		Properties:           make(map[string]*Schema),
+		AdditionalProperties: false,
+	}
+
+	for _, d := range Defaults {
+		path := strings.Split(d.Key, ".")
+		node := sectionSchema(root, path[:len(path)-1])
+		node.Properties[path[len(path)-1]] = valueSchema(d)
+		node.Properties[path[len(path)-1]].Enum = schemaEnum(d.Key)
+	}
+
+	for _, example := range Examples {
+		path := strings.Split(example.Key, ".")
+		node := sectionSchema(root, path[:len(path)-1])
+		if example.List {
+			node.Properties[path[len(path)-1]] = &Schema{
+				Type:        "array",
+				Description: Sections[example.Key],
+				Items:       objectSchema(example.Defaults),
+			}
+		} else {
+			node.Properties[path[len(path)-1]] = &Schema{
+				Type:                 "object",
+				Description:          Sections[example.Key],
+				AdditionalProperties: objectSchema(example.Defaults),
+			}
+		}
+	}
+
+	return root
+}
+
+// sectionSchema returns the schema of the section at the given path, creating the sections that are missing.
+func sectionSchema(root *Schema, path []string) *Schema {
+	node := root
+	for i, section := range path {
+		child, ok := node.Properties[section]
+		if !ok {
+			child = &Schema{
+				Type:                 "object",
+				Description:          Sections[strings.Join(path[:i+1], ".")],
+				Properties:           make(map[string]*Schema),
+				AdditionalProperties: false,
+			}
+			node.Properties[section] = child
+		}
+		node = child
+	}
+	return node
+}
+
+// objectSchema returns the schema of a section whose keys are the given defaults.
+func objectSchema(defaults []Default) *Schema {
+	object := &Schema{
+		Type:                 "object",
+		Properties:           make(map[string]*Schema),
+		AdditionalProperties: false,
+	}
+	for _, d := range defaults {
+		object.Properties[d.Key] = valueSchema(d)
+	}
+	return object
+}
+
+func valueSchema(d Default) *Schema {
+	schema := &Schema{
+		Type:        schemaType(d.Value),
+		Description: d.Comment,
+		Default:     d.Value,
+	}
+	if schema.Type == "array" {
+		schema.Items = &Schema{Type: "string"}
+	}
+	return schema
+}
+
+func schemaType(value interface{}) string {
+	switch value.(type) {
+	case []string:
+		return "array"
+	case bool:
+		return "boolean"
+	case int, int64:
+		return "integer"
+	case float64:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+func schemaEnum(key string) []interface{} {
+	var enum []interface{}
+	switch key {
+	// storm.adaptive.predictive_model has no enum: it may name a model of predictor.catalog, which ValidateConfig
+	// checks
+	case "workload.kind":
+		for _, kind := range []string{"constant", "sinusoidal", "step", "poisson", "flash_crowd", "seasonal"} {
+			enum = append(enum, kind)
+		}
+	case "storm.adaptive.degradation":
+		enum = append(enum, "output", "acked")
+	case "storm.adaptive.baseline":
+		enum = append(enum, "config", "initial", "max")
+	case "backend":
+		enum = append(enum, "storm", "flink", "heron", "pulsar", "spark")
+	case "cost.provider":
+		enum = append(enum, "static", "aws", "gcp")
+	case "metrics_source":
+		enum = append(enum, "cluster", "prometheus")
+	case "storm.adaptive.latency_source":
+		enum = append(enum, "rest", "complete")
+	case "storm.adaptive.input_rate_source":
+		enum = append(enum, "spout", "kafka", "mqtt", "nats", "http")
+	case "mqtt.counter":
+		enum = append(enum, "sys", "topics")
+	case "storm.adaptive.planner":
+		enum = append(enum, "internal", "external")
+	case "kubernetes.workers.kind":
+		enum = append(enum, "statefulsets", "deployments")
+	case "storm.adaptive.preset":
+		var names []string
+		for name := range Presets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		enum = append(enum, "")
+		for _, name := range names {
+			enum = append(enum, name)
+		}
+	}
+	return enum
+}
+
+// ValidateConfigFile validates the keys and values written in the config file against the config schema, so
+// that typos in a key are reported instead of silently falling back to the default.
+func ValidateConfigFile() []error {
+	if viper.ConfigFileUsed() == "" {
+		return nil
+	}
+
+	file := viper.New()
+	file.SetConfigFile(viper.ConfigFileUsed())
+	if err := file.ReadInConfig(); err != nil {
+		return []error{err}
+	}
+
+	return validateSchema(ConfigSchema(), file.AllSettings(), "")
+}
+
+func validateSchema(schema *Schema, value interface{}, path string) []error {
+	var errs []error
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []error{fmt.Errorf("%s: must be a section", path)}
+		}
+		var keys []string
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			keyPath := strings.TrimPrefix(path+"."+key, ".")
+			if property, ok := schema.Properties[key]; ok {
+				errs = append(errs, validateSchema(property, object[key], keyPath)...)
+			} else if additional, ok := schema.AdditionalProperties.(*Schema); ok {
+				errs = append(errs, validateSchema(additional, object[key], keyPath)...)
+			} else if schema.AdditionalProperties == false {
+				errs = append(errs, fmt.Errorf("%s: unknown key", keyPath))
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return []error{fmt.Errorf("%s: must be a list", path)}
+		}
+		for i := range array {
+			errs = append(errs, validateSchema(schema.Items, array[i], fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case "integer":
+		switch v := value.(type) {
+		case int, int64:
+		case float64:
+			if v != float64(int64(v)) {
+				errs = append(errs, fmt.Errorf("%s: must be an integer, got %v", path, value))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("%s: must be an integer, got %v", path, value))
+		}
+	case "number":
+		switch value.(type) {
+		case int, int64, float64:
+		default:
+			errs = append(errs, fmt.Errorf("%s: must be a number, got %v", path, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			errs = append(errs, fmt.Errorf("%s: must be a boolean, got %v", path, value))
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			errs = append(errs, fmt.Errorf("%s: must be a string, got %v", path, value))
+		}
+	}
+
+	if len(errs) == 0 && len(schema.Enum) > 0 {
+		var known bool
+		for _, enum := range schema.Enum {
+			if reflect.DeepEqual(enum, value) {
+				known = true
+			}
+		}
+		if !known {
+			errs = append(errs, fmt.Errorf("%s: must be one of %v, got %v", path, schema.Enum, value))
+		}
+	}
+
+	return errs
+}
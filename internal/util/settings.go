@@ -0,0 +1,130 @@
+package util
+
+import (
+	"github.com/spf13/viper"
+	"log"
+	"time"
+)
+
+// Settings are the parameters read on every window by the adaptive system. They are loaded once from the config
+// with LoadSettings, so each window does not look them up in viper or decode the sections of the bolts and the
+// schedules again.
+type Settings struct {
+	TimeWindowSize     int64
+	BenchmarkSamples   int
+	AnalyzeSamples     int
+	PlanningSamples    int
+	PredictionNumber   int
+	BaselineReplicas   int64
+	Baseline           string
+	SlaLatency         float64
+	SlaLag             int64
+	SlaWeights         SlaTerms // storm.adaptive.sla_score.weights
+	SlaFloors          SlaTerms // storm.adaptive.sla_score.floors
+	InputRateSource    string
+	InputRateSmoothing float64
+	LatencySource      string
+	OutputBolts        []string
+	Degradation        string
+	Planner            string
+	Analyze            bool
+	Exporters          bool
+	Reports            bool
+	DryRun             bool
+	NatsConsumer       string
+	PlanLag            bool
+	Headroom           float64
+	ScaleDownCooldown  int
+	RebalanceBlackout  int
+	DegradedThreshold  float64
+	SafeDegraded       int // storm.adaptive.safe_mode.degraded_windows
+	SafeRebalances     int // storm.adaptive.safe_mode.failed_rebalances
+	WatchInterval      int // storm.adaptive.watch.interval
+	WatchTimeout       int // storm.adaptive.watch.timeout
+	DecisionsLimit     int // storm.adaptive.decisions_limit
+	GpuSlots           int64
+	Federation         bool
+	ReplicaBudget      int64 // federation.replica_budget
+	Stagger            int   // federation.stagger
+	Schedules          []Schedule
+	bolts              map[string]BoltConfig
+}
+
+// SlaTerms are the values of each term of the SLA score: the latency, the degradation and the lag.
+type SlaTerms struct {
+	Latency     float64
+	Degradation float64
+	Lag         float64
+}
+
+func slaTerms(key string) SlaTerms {
+	return SlaTerms{
+		Latency:     viper.GetFloat64(key + ".latency"),
+		Degradation: viper.GetFloat64(key + ".degradation"),
+		Lag:         viper.GetFloat64(key + ".lag"),
+	}
+}
+
+// LoadSettings reads the Settings from the config.
+func LoadSettings() Settings {
+	s := Settings{
+		TimeWindowSize:     viper.GetInt64("storm.adaptive.time_window_size"),
+		BenchmarkSamples:   viper.GetInt("storm.adaptive.benchmark_samples"),
+		AnalyzeSamples:     viper.GetInt("storm.adaptive.analyze_samples"),
+		PlanningSamples:    viper.GetInt("storm.adaptive.planning_samples"),
+		PredictionNumber:   viper.GetInt("storm.adaptive.prediction_number"),
+		BaselineReplicas:   viper.GetInt64("storm.adaptive.baseline_replicas"),
+		Baseline:           viper.GetString("storm.adaptive.baseline"),
+		SlaLatency:         viper.GetFloat64("storm.adaptive.sla_latency"),
+		SlaLag:             viper.GetInt64("storm.adaptive.sla_lag"),
+		SlaWeights:         slaTerms("storm.adaptive.sla_score.weights"),
+		SlaFloors:          slaTerms("storm.adaptive.sla_score.floors"),
+		InputRateSource:    viper.GetString("storm.adaptive.input_rate_source"),
+		InputRateSmoothing: viper.GetFloat64("storm.adaptive.input_rate_smoothing"),
+		LatencySource:      viper.GetString("storm.adaptive.latency_source"),
+		OutputBolts:        viper.GetStringSlice("storm.adaptive.output_bolts"),
+		Degradation:        viper.GetString("storm.adaptive.degradation"),
+		Planner:            viper.GetString("storm.adaptive.planner"),
+		Analyze:            viper.GetBool("storm.deploy.analyze"),
+		Exporters:          viper.GetBool("features.exporters"),
+		Reports:            viper.GetBool("features.reports"),
+		DryRun:             viper.GetBool("dry_run"),
+		NatsConsumer:       viper.GetString("nats.consumer"),
+		PlanLag:            viper.GetBool("nats.plan_lag"),
+		Headroom:           viper.GetFloat64("storm.adaptive.headroom"),
+		ScaleDownCooldown:  viper.GetInt("storm.adaptive.scale_down_cooldown"),
+		RebalanceBlackout:  viper.GetInt("storm.adaptive.rebalance_blackout"),
+		DegradedThreshold:  viper.GetFloat64("storm.adaptive.degraded_threshold"),
+		SafeDegraded:       viper.GetInt("storm.adaptive.safe_mode.degraded_windows"),
+		SafeRebalances:     viper.GetInt("storm.adaptive.safe_mode.failed_rebalances"),
+		WatchInterval:      viper.GetInt("storm.adaptive.watch.interval"),
+		WatchTimeout:       viper.GetInt("storm.adaptive.watch.timeout"),
+		DecisionsLimit:     viper.GetInt("storm.adaptive.decisions_limit"),
+		GpuSlots:           viper.GetInt64("storm.adaptive.gpu_slots"),
+		Federation:         viper.GetBool("features.federation"),
+		ReplicaBudget:      viper.GetInt64("federation.replica_budget"),
+		Stagger:            viper.GetInt("federation.stagger"),
+		bolts:              make(map[string]BoltConfig),
+	}
+	if err := UnmarshalSection("storm.adaptive.schedules", &s.Schedules); err != nil {
+		log.Printf("config: schedules error={%v}\n", err)
+	}
+	return s
+}
+
+// Bolt returns the configuration of the bolt, decoded from the config on its first use.
+func (s Settings) Bolt(name string) BoltConfig {
+	if config, ok := s.bolts[name]; ok {
+		return config
+	}
+	config := GetBoltConfig(name)
+	if s.bolts != nil {
+		s.bolts[name] = config
+	}
+	return config
+}
+
+// Profile returns the adaptive parameters at the given time, as GetProfile.
+func (s Settings) Profile(t time.Time) Profile {
+	return profileAt(t, Profile{Name: "default", Headroom: s.Headroom, ScaleDownCooldown: s.ScaleDownCooldown, BaselineReplicas: s.BaselineReplicas}, s.Schedules)
+}
@@ -0,0 +1,63 @@
+package util
+
+import (
+	"math"
+	"sort"
+)
+
+// Sketch is a quantile sketch with relative accuracy (DDSketch): each sample is counted in a bucket of
+// logarithmic width, so any quantile is estimated within the relative error of the sketch, and the memory only
+// grows with the logarithm of the range of the samples, not with their number.
+type Sketch struct {
+	gamma   float64
+	buckets map[int]uint64
+	zeros   uint64 // samples <= 0
+	count   uint64
+}
+
+// NewSketch returns an empty Sketch with the given relative accuracy (e.g. 0.01 for 1%).
+func NewSketch(accuracy float64) *Sketch {
+	return &Sketch{gamma: (1 + accuracy) / (1 - accuracy), buckets: make(map[int]uint64)}
+}
+
+// Add adds a sample. NaN samples are ignored.
+func (s *Sketch) Add(sample float64) {
+	if math.IsNaN(sample) {
+		return
+	}
+	s.count++
+	if sample <= 0 {
+		s.zeros++
+		return
+	}
+	s.buckets[int(math.Ceil(math.Log(sample)/math.Log(s.gamma)))]++
+}
+
+func (s *Sketch) Count() uint64 {
+	return s.count
+}
+
+// Quantile returns the estimated q-quantile of the samples, between 0 and 1, and 0 when it is empty.
+func (s *Sketch) Quantile(q float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(s.count-1))
+	if rank < s.zeros {
+		return 0
+	}
+	var keys []int
+	for key := range s.buckets {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+
+	seen := s.zeros
+	for _, key := range keys {
+		seen += s.buckets[key]
+		if seen > rank {
+			return 2 * math.Pow(s.gamma, float64(key)) / (s.gamma + 1)
+		}
+	}
+	return 2 * math.Pow(s.gamma, float64(keys[len(keys)-1])) / (s.gamma + 1)
+}
@@ -0,0 +1,59 @@
+package util
+
+import "math"
+
+// Welford accumulates the mean and the variance of samples incrementally (Welford's algorithm), so adding or
+// removing a sample is O(1) and it does not keep the samples. The zero Welford holds no samples.
+type Welford struct {
+	n    int
+	mean float64
+	m2   float64
+}
+
+// Add adds a sample.
+func (w *Welford) Add(sample float64) {
+	w.n++
+	delta := sample - w.mean
+	w.mean += delta / float64(w.n)
+	w.m2 += delta * (sample - w.mean)
+}
+
+// Remove removes a sample that was added before, e.g. the oldest sample of a sliding window.
+func (w *Welford) Remove(sample float64) {
+	if w.n <= 1 {
+		*w = Welford{}
+		return
+	}
+	delta := sample - w.mean
+	w.mean -= delta / float64(w.n-1)
+	w.m2 -= delta * (sample - w.mean)
+	w.n--
+	if w.m2 < 0 {
+		w.m2 = 0
+	}
+}
+
+func (w *Welford) Len() int {
+	return w.n
+}
+
+// Mean returns the mean of the samples, NaN when it is empty (as stats.Mean).
+func (w *Welford) Mean() float64 {
+	if w.n == 0 {
+		return math.NaN()
+	}
+	return w.mean
+}
+
+// Variance returns the population variance of the samples, NaN when it is empty.
+func (w *Welford) Variance() float64 {
+	if w.n == 0 {
+		return math.NaN()
+	}
+	return w.m2 / float64(w.n)
+}
+
+// StdDev returns the population standard deviation of the samples (as stats.StandardDeviation).
+func (w *Welford) StdDev() float64 {
+	return math.Sqrt(w.Variance())
+}
@@ -0,0 +1,86 @@
+package workload
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Spec describes a synthetic input-rate trace, in tuples per time window.
+type Spec struct {
+	Kind             string  `mapstructure:"kind"` // constant, sinusoidal, step, poisson, flash_crowd, seasonal
+	Length           int     `mapstructure:"length"`
+	Base             float64 `mapstructure:"base"`
+	Amplitude        float64 `mapstructure:"amplitude"`
+	Period           float64 `mapstructure:"period"`
+	StepAt           int     `mapstructure:"step_at"`
+	Trend            float64 `mapstructure:"trend"`
+	Noise            float64 `mapstructure:"noise"`
+	BurstProbability float64 `mapstructure:"burst_probability"`
+	BurstDuration    int     `mapstructure:"burst_duration"`
+	BurstFactor      float64 `mapstructure:"burst_factor"`
+	Seed             int64   `mapstructure:"seed"`
+}
+
+// Generate returns the trace described by the spec. The same spec, seed included, always generates the same trace.
+func Generate(spec Spec) ([]int64, error) {
+	if spec.Length < 1 {
+		return nil, fmt.Errorf("workload: length must be positive")
+	}
+	if spec.Period <= 0 {
+		spec.Period = float64(spec.Length)
+	}
+	r := rand.New(rand.NewSource(spec.Seed))
+
+	trace := make([]int64, spec.Length)
+	var burst int
+	for t := range trace {
+		var rate float64
+		switch spec.Kind {
+		case "constant":
+			rate = spec.Base
+		case "sinusoidal":
+			rate = spec.Base + spec.Amplitude*math.Sin(2*math.Pi*float64(t)/spec.Period)
+		case "step":
+			rate = spec.Base
+			if t >= spec.StepAt {
+				rate += spec.Amplitude
+			}
+		case "poisson":
+			rate = poisson(r, spec.Base)
+		case "flash_crowd":
+			if burst == 0 && r.Float64() < spec.BurstProbability {
+				burst = spec.BurstDuration
+			}
+			rate = spec.Base
+			if burst > 0 {
+				rate *= spec.BurstFactor
+				burst--
+			}
+		case "seasonal":
+			rate = spec.Base + spec.Trend*float64(t) + spec.Amplitude*math.Sin(2*math.Pi*float64(t)/spec.Period)
+		default:
+			return nil, fmt.Errorf("workload: unknown kind %q", spec.Kind)
+		}
+
+		if spec.Noise > 0 {
+			rate += r.NormFloat64() * spec.Noise * rate
+		}
+		trace[t] = int64(math.Max(0, math.Round(rate)))
+	}
+
+	return trace, nil
+}
+
+// poisson samples a Poisson distribution, with the normal approximation for large means.
+func poisson(r *rand.Rand, lambda float64) float64 {
+	if lambda > 500 {
+		return math.Round(lambda + r.NormFloat64()*math.Sqrt(lambda))
+	}
+	l := math.Exp(-lambda)
+	k := 0.0
+	for p := r.Float64(); p > l; p *= r.Float64() {
+		k++
+	}
+	return k
+}
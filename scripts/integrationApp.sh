@@ -0,0 +1,2 @@
+docker exec sps-nimbus storm kill -w 0 testingApp
+docker exec sps-nimbus storm jar /apps/testingApp.jar com.github.dwladdimiroc.stormTestingApp.topology.LinearTopology $1 $2